@@ -0,0 +1,110 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// keysDirective marks keys the code builds at runtime, e.g.
+//
+//	//i18n:keys error_404_title error_404_body
+const keysDirective = "//i18n:keys"
+
+type usage struct {
+	keys    map[string][]token.Position
+	dynamic []token.Position
+}
+
+// extract finds the translation keys used by the Go source under root:
+// string literals passed to t, to a .T field or method, or to a call result
+// such as h.t(c)("key"), plus the keys listed in //i18n:keys comments.
+// Lookups with a computed key are recorded as dynamic.
+func extract(root string) (*usage, error) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" || d.Name() == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u := &usage{keys: map[string][]token.Position{}}
+	add := func(key string, pos token.Pos) {
+		u.keys[key] = append(u.keys[key], fset.Position(pos))
+	}
+	for _, f := range files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if rest, ok := strings.CutPrefix(c.Text, keysDirective); ok {
+					for _, k := range strings.Fields(rest) {
+						add(k, c.Pos())
+					}
+				}
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			name := ""
+			switch fn := call.Fun.(type) {
+			case *ast.Ident:
+				name = fn.Name
+			case *ast.SelectorExpr:
+				if fn.Sel.Name == "T" {
+					name = "T"
+				}
+			case *ast.CallExpr:
+				name = "t"
+			}
+			if name != "t" && name != "T" {
+				return true
+			}
+			// Bundle.T takes the language first; an i18n.Func the key.
+			arg := call.Args[0]
+			if _, ok := stringLit(arg); !ok && name == "T" && len(call.Args) > 1 {
+				arg = call.Args[1]
+			}
+			if k, ok := stringLit(arg); ok {
+				add(k, arg.Pos())
+			} else {
+				u.dynamic = append(u.dynamic, fset.Position(arg.Pos()))
+			}
+			return true
+		})
+	}
+	return u, nil
+}
+
+func stringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
@@ -0,0 +1,100 @@
+// Command i18n checks the message bundles against the keys the Go source
+// uses. For each language it lists keys that are used (or defined in en) but
+// missing, and keys that nothing uses. It exits 1 when anything is reported,
+// so it can gate releases.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"go-next-cms/internal/i18n"
+)
+
+func main() {
+	dir := flag.String("dir", "i18n", "directory of <lang>.json bundles")
+	src := flag.String("src", ".", "root of the Go source to scan for keys")
+	stubs := flag.Bool("stubs", false, "print the missing keys of each language as JSON stubs holding the en message")
+	verbose := flag.Bool("v", false, "also list lookups whose key is computed at runtime")
+	flag.Parse()
+
+	b, err := i18n.Load(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	u, err := extract(*src)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	en := b.Messages("en")
+	problems := 0
+	out := map[string]map[string]string{}
+	for _, lang := range b.Languages() {
+		msgs := b.Messages(lang)
+		var missing, unused []string
+		for k := range u.keys {
+			if _, ok := msgs[k]; !ok {
+				missing = append(missing, k)
+			}
+		}
+		if lang != "en" {
+			for k := range en {
+				if _, ok := msgs[k]; !ok {
+					if _, used := u.keys[k]; !used {
+						missing = append(missing, k)
+					}
+				}
+			}
+		}
+		for k := range msgs {
+			if _, ok := u.keys[k]; !ok {
+				unused = append(unused, k)
+			}
+		}
+		sort.Strings(missing)
+		sort.Strings(unused)
+		problems += len(missing) + len(unused)
+		fmt.Fprintf(os.Stderr, "%s: %d messages, %d missing, %d unused\n", lang, len(msgs), len(missing), len(unused))
+		for _, k := range missing {
+			where := ""
+			if pos := u.keys[k]; len(pos) > 0 {
+				where = " (" + pos[0].String() + ")"
+			}
+			fmt.Fprintf(os.Stderr, "  missing %s%s\n", k, where)
+		}
+		for _, k := range unused {
+			fmt.Fprintf(os.Stderr, "  unused  %s\n", k)
+		}
+		if len(missing) > 0 {
+			out[lang] = map[string]string{}
+			for _, k := range missing {
+				stub, ok := en[k]
+				if !ok {
+					stub = k
+				}
+				out[lang][k] = stub
+			}
+		}
+	}
+	if *verbose {
+		for _, pos := range u.dynamic {
+			fmt.Fprintf(os.Stderr, "dynamic key at %s\n", pos)
+		}
+	}
+	if *stubs && len(out) > 0 {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if problems > 0 {
+		os.Exit(1)
+	}
+}
@@ -5,19 +5,28 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"go-next-cms/internal/auth"
 	"go-next-cms/internal/config"
 	"go-next-cms/internal/db"
+	"go-next-cms/internal/geoip"
+	"go-next-cms/internal/http/api"
 	"go-next-cms/internal/http/handlers"
 	"go-next-cms/internal/http/middleware"
 	"go-next-cms/internal/i18n"
+	"go-next-cms/internal/jobs"
+	"go-next-cms/internal/mail"
+	"go-next-cms/internal/models"
 	"go-next-cms/internal/repo"
 	"go-next-cms/internal/service"
 	"go-next-cms/internal/storage"
+	"go-next-cms/internal/translate"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 )
 
 func main() {
@@ -30,58 +39,144 @@ func main() {
 	defer pool.Close()
 
 	r := repo.New(pool)
+	r.HideUnreviewedTranslations = cfg.HideUnreviewed
 	bundle, err := i18n.Load("i18n")
 	if err != nil {
 		log.Fatal(err)
 	}
-	svc := service.New(r)
-	sessions := middleware.SessionStore()
-	h := handlers.New(r, svc, bundle, sessions, storage.LocalUploader{Dir: cfg.UploadDir, BasePath: "/static/uploads", MaxSize: cfg.MaxUploadBytes})
+	bundle.Debug = cfg.I18nDebug
+	if bundle.Fallbacks, err = i18n.ParseFallbacks(cfg.LangFallbacks); err != nil {
+		log.Fatalf("LANG_FALLBACKS: %v", err)
+	}
+	svc := service.New(r, mail.New(cfg), cfg.BaseURL)
+	if cfg.TOTPKey != "" {
+		if svc.TOTPKey, err = auth.ParseSecretKey(cfg.TOTPKey); err != nil {
+			log.Fatalf("TOTP_KEY: %v", err)
+		}
+	}
+	uploads := storage.LocalUploader{Dir: cfg.UploadDir, BasePath: "/static/uploads", MaxSize: cfg.MaxUploadBytes}
+	scheduler := jobs.New(r)
+	for _, j := range append(jobs.DealJobs(svc, uploads), jobs.SessionJobs(r)...) {
+		scheduler.Register(j)
+	}
+	translator, err := translate.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if translator != nil {
+		for _, j := range jobs.TranslationJobs(r, translator, bundle.Languages()) {
+			scheduler.Register(j)
+		}
+	}
+	sessions := middleware.SessionStore(cfg, r.SessionStorage())
+	var geo *geoip.DB
+	if cfg.GeoIPFile != "" {
+		if geo, err = geoip.Load(cfg.GeoIPFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	h := handlers.New(r, svc, bundle, sessions, uploads, cfg.DefaultCountry, cfg.DefaultLang, geo)
 
-	app := fiber.New()
+	a := api.New(r, svc, bundle)
+	fcfg := fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		if strings.HasPrefix(c.Path(), "/api/") {
+			return a.Error(c, err)
+		}
+		return h.Error(c, err)
+	}}
+	if len(cfg.TrustedProxies) > 0 {
+		// c.IP() reads the client address from ProxyHeader, but only on
+		// requests that come from one of the trusted proxies.
+		fcfg.EnableTrustedProxyCheck = true
+		fcfg.TrustedProxies = cfg.TrustedProxies
+		fcfg.ProxyHeader = cfg.ProxyHeader
+		fcfg.EnableIPValidation = true
+	}
+	app := fiber.New(fcfg)
+	app.Use(requestid.New())
 	app.Use(middleware.StructuredLogger())
-	app.Use(middleware.AttachUser(sessions, r))
+	// Static files come first so they skip the session and user lookup.
 	app.Static("/static", "./static")
+	app.Use(middleware.AttachUser(sessions, r))
 
-	app.Get("/", func(c *fiber.Ctx) error { return c.Redirect("/LK") })
-	app.Get("/:countryCode", h.Home)
-	app.Get("/:countryCode/deals", h.Deals)
-	app.Get("/:countryCode/city/:city", func(c *fiber.Ctx) error { c.Context().QueryArgs().Set("city", c.Params("city")); return h.Deals(c) })
-	app.Get("/:countryCode/category/:categorySlug", func(c *fiber.Ctx) error {
-		c.Context().QueryArgs().Set("category", c.Params("categorySlug"))
-		return h.Deals(c)
-	})
-	app.Get("/:countryCode/deal/:dealSlug", h.DealDetail)
+	a.Routes(app.Group(api.BasePath, middleware.BearerAuth(r), middleware.APIRateLimit(cfg.APIAnonRateLimit)))
+	app.Get("/api/openapi.json", a.OpenAPI)
 
 	account := app.Group("/account", middleware.CSRFMiddleware(sessions))
 	account.Get("/register", h.RegisterForm)
 	account.Post("/register", h.Register)
 	account.Get("/login", h.LoginForm)
 	account.Post("/login", h.Login)
+	account.Get("/login/2fa", h.SecondFactorForm)
+	account.Post("/login/2fa", h.SecondFactor)
 	account.Get("/logout", h.Logout)
+	account.Get("/cities", h.CityOptions)
+	account.Get("/translations", h.TranslationInputs)
+	account.Get("/2fa", middleware.RequireAuth(), h.TwoFactorSettings)
+	account.Post("/2fa", middleware.RequireAuth(), h.EnableTwoFactor)
+	account.Post("/2fa/disable", middleware.RequireAuth(), h.DisableTwoFactor)
+	account.Get("/sessions", middleware.RequireAuth(), h.SessionList)
+	account.Post("/sessions/:id/revoke", middleware.RequireAuth(), h.RevokeSession)
 	account.Get("/submissions", middleware.RequireAuth(), h.SubmissionList)
-	account.Get("/submissions/new", middleware.RequireAuth(), h.NewSubmissionForm)
-	account.Post("/submissions/new", middleware.RequireAuth(), h.CreateSubmission)
+	account.Get("/verify", h.VerifyEmail)
+	account.Post("/verify", middleware.RequireAuth(), h.ResendVerification)
+	account.Get("/reset", h.ResetForm)
+	account.Post("/reset", h.ResetPassword)
+	verified := middleware.RequireVerifiedEmail(cfg.RequireVerifiedMail)
+	account.Get("/submissions/new", middleware.RequireAuth(), verified, h.NewSubmissionForm)
+	account.Post("/submissions/new", middleware.RequireAuth(), verified, h.CreateSubmission)
 	account.Get("/submissions/:id/edit", middleware.RequireAuth(), h.EditSubmissionForm)
 	account.Post("/submissions/:id/edit", middleware.RequireAuth(), h.UpdateSubmission)
 
-	admin := app.Group("/admin", middleware.RequireAuth(), middleware.RequireAdmin(), middleware.CSRFMiddleware(sessions))
+	admin := app.Group("/admin", middleware.RequireAuth(), middleware.RequireStaff(cfg.RequireAdmin2FA), middleware.CSRFMiddleware(sessions))
+	moderate := middleware.RequirePermission(models.PermModerate)
+	manageUsers := middleware.RequirePermission(models.PermManageUsers)
+	manageMaster := middleware.RequirePermission(models.PermManageMasterData)
+	editConfig := middleware.RequirePermission(models.PermEditConfig)
+	translateDeals := middleware.RequirePermission(models.PermTranslate)
 	admin.Get("/", h.AdminDashboard)
-	admin.Get("/moderation", h.AdminModeration)
-	admin.Post("/moderation/:id", h.AdminModerate)
-	admin.Get("/users", h.AdminUsers)
-	admin.Post("/users/:id/role", h.AdminUserRole)
-	admin.Get("/config", h.AdminConfig)
-	admin.Post("/config", h.SaveConfig)
-	admin.Get("/master", h.AdminMaster)
-	admin.Post("/master/country", h.CreateCountry)
-	admin.Post("/master/city", h.CreateCity)
-	admin.Post("/master/category", h.CreateCategory)
-	admin.Post("/master/merchant", h.CreateMerchant)
-	admin.Post("/master/dealtype", h.CreateDealType)
-	admin.Get("/deals/new", h.AdminNewDealForm)
-	admin.Post("/deals/new", h.AdminCreateDeal)
+	admin.Get("/moderation", moderate, h.AdminModeration)
+	admin.Post("/moderation/:id", moderate, h.AdminModerate)
+	admin.Get("/translations", translateDeals, h.AdminTranslations)
+	admin.Post("/translations/:id/:lang", translateDeals, h.AdminReviewTranslation)
+	admin.Get("/users", manageUsers, h.AdminUsers)
+	admin.Post("/users/:id/role", manageUsers, h.AdminUserRole)
+	admin.Post("/users/:id/logout", manageUsers, h.AdminForceLogout)
+	admin.Post("/users/:id/unlock", manageUsers, h.AdminUnlockUser)
+	admin.Get("/users/logins", manageUsers, h.AdminLoginAudit)
+	admin.Get("/users/:id/countries", manageUsers, h.AdminUserCountries)
+	admin.Post("/users/:id/countries", manageUsers, h.SaveUserCountries)
+	admin.Get("/users/:id/tokens", manageUsers, h.AdminUserTokens)
+	admin.Post("/users/:id/tokens", manageUsers, h.CreateAPIToken)
+	admin.Post("/users/:id/tokens/:tokenID/revoke", manageUsers, h.RevokeAPIToken)
+	admin.Get("/config", editConfig, h.AdminConfig)
+	admin.Post("/config", editConfig, h.SaveConfig)
+	admin.Get("/master", manageMaster, h.AdminMaster)
+	admin.Post("/master/country", manageMaster, h.CreateCountry)
+	admin.Post("/master/country/:id/languages", manageMaster, h.SaveCountryLanguages)
+	admin.Post("/master/city", manageMaster, h.CreateCity)
+	admin.Post("/master/category", manageMaster, h.CreateCategory)
+	admin.Post("/master/merchant", manageMaster, h.CreateMerchant)
+	admin.Post("/master/dealtype", manageMaster, h.CreateDealType)
+	admin.Post("/master/:entity/:id/translations", manageMaster, h.SaveMasterTranslations)
+	admin.Get("/deals/new", moderate, h.AdminNewDealForm)
+	admin.Post("/deals/new", moderate, h.AdminCreateDeal)
+	admin.Get("/deals/:id/revisions", moderate, h.AdminDealRevisions)
+	admin.Post("/deals/:id/revisions/:rev/restore", moderate, h.AdminRestoreRevision)
+
+	// Country routes go last so /:countryCode does not shadow /account and /admin.
+	app.Get("/", h.Root)
+	known := middleware.KnownCountry(svc)
+	app.Get("/:countryCode", known, h.Home)
+	app.Get("/:countryCode/deals", known, h.Deals)
+	app.Get("/:countryCode/city/:city", known, func(c *fiber.Ctx) error { c.Context().QueryArgs().Set("city", c.Params("city")); return h.Deals(c) })
+	app.Get("/:countryCode/category/:categorySlug", known, func(c *fiber.Ctx) error {
+		c.Context().QueryArgs().Set("category", c.Params("categorySlug"))
+		return h.Deals(c)
+	})
+	app.Get("/:countryCode/deal/:dealSlug", known, h.DealDetail)
 
+	scheduler.Start(ctx)
 	go func() {
 		if err := app.Listen(cfg.Addr()); err != nil {
 			log.Fatal(err)
@@ -92,4 +187,5 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	_ = app.ShutdownWithTimeout(5 * time.Second)
+	scheduler.Stop()
 }
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedPrefix marks a secret sealed by SealSecret; stored secrets without it
+// predate encryption and are plaintext.
+const sealedPrefix = "v1:"
+
+var ErrSecretKey = errors.New("secret key must be 32 bytes, base64 encoded")
+
+// ParseSecretKey decodes the base64 AES-256 key used to seal TOTP secrets.
+func ParseSecretKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != 32 {
+		return nil, ErrSecretKey
+	}
+	return key, nil
+}
+
+// SealSecret encrypts secret with AES-GCM under key for storage.
+func SealSecret(key []byte, secret string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// OpenSecret decrypts a secret stored by SealSecret. A stored secret from
+// before sealing is returned as is, with legacy set so callers can seal it.
+func OpenSecret(key []byte, stored string) (secret string, legacy bool, err error) {
+	raw, ok := strings.CutPrefix(stored, sealedPrefix)
+	if !ok {
+		return stored, true, nil
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", false, err
+	}
+	b, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil || len(b) < gcm.NonceSize() {
+		return "", false, errors.New("malformed sealed secret")
+	}
+	plain, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", false, err
+	}
+	return string(plain), false, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, ErrSecretKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
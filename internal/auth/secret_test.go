@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte { return bytes.Repeat([]byte{b}, 32) }
+
+func TestSealOpenSecret(t *testing.T) {
+	key := testKey(1)
+	sealed, err := SealSecret(key, rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, sealedPrefix) || strings.Contains(sealed, rfcSecret) {
+		t.Fatalf("sealed secret %q", sealed)
+	}
+	again, err := SealSecret(key, rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Error("sealing twice gave the same ciphertext; nonce is not random")
+	}
+	got, legacy, err := OpenSecret(key, sealed)
+	if err != nil || legacy || got != rfcSecret {
+		t.Errorf("OpenSecret = %q, %v, %v; want %q, false, nil", got, legacy, err, rfcSecret)
+	}
+}
+
+func TestOpenSecretWrongKey(t *testing.T) {
+	sealed, err := SealSecret(testKey(1), rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := OpenSecret(testKey(2), sealed); err == nil {
+		t.Errorf("OpenSecret with the wrong key = %q, want error", got)
+	}
+	if _, _, err := OpenSecret(testKey(1)[:16], sealed); !errors.Is(err, ErrSecretKey) {
+		t.Errorf("OpenSecret with a short key: err = %v, want %v", err, ErrSecretKey)
+	}
+}
+
+func TestOpenSecretMalformed(t *testing.T) {
+	for _, stored := range []string{sealedPrefix + "not base64!", sealedPrefix + "AAAA", sealedPrefix} {
+		if _, _, err := OpenSecret(testKey(1), stored); err == nil {
+			t.Errorf("OpenSecret(%q) succeeded", stored)
+		}
+	}
+}
+
+func TestOpenSecretLegacy(t *testing.T) {
+	got, legacy, err := OpenSecret(testKey(1), rfcSecret)
+	if err != nil || !legacy || got != rfcSecret {
+		t.Errorf("OpenSecret(plaintext) = %q, %v, %v; want %q, true, nil", got, legacy, err, rfcSecret)
+	}
+}
+
+func TestParseSecretKey(t *testing.T) {
+	good := base64.StdEncoding.EncodeToString(testKey(3))
+	if key, err := ParseSecretKey(" " + good + "\n"); err != nil || !bytes.Equal(key, testKey(3)) {
+		t.Errorf("ParseSecretKey(valid) = %x, %v", key, err)
+	}
+	for _, s := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(testKey(3)[:16])} {
+		if _, err := ParseSecretKey(s); !errors.Is(err, ErrSecretKey) {
+			t.Errorf("ParseSecretKey(%q): err = %v, want %v", s, err, ErrSecretKey)
+		}
+	}
+}
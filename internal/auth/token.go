@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+const tokenPrefix = "dl_"
+
+// GenerateAPIToken returns a token of the form dl_<prefix>_<secret>. Only the
+// prefix and the SHA-256 hash of the whole token are meant to be stored.
+func GenerateAPIToken() (token, prefix, hash string, err error) {
+	p := make([]byte, 4)
+	s := make([]byte, 24)
+	if _, err = rand.Read(p); err != nil {
+		return
+	}
+	if _, err = rand.Read(s); err != nil {
+		return
+	}
+	prefix = hex.EncodeToString(p)
+	token = tokenPrefix + prefix + "_" + hex.EncodeToString(s)
+	return token, prefix, HashAPIToken(token), nil
+}
+
+func HashAPIToken(token string) string { return HashToken(token) }
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewOneTimeToken returns a random URL-safe token and the hash to store.
+func NewOneTimeToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+func APITokenPrefix(token string) (string, bool) {
+	rest, ok := strings.CutPrefix(token, tokenPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	return prefix, ok && prefix != ""
+}
+
+func CheckAPIToken(hash, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(HashAPIToken(token))) == 1
+}
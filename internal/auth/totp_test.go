@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 SHA1 test key "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 appendix B vectors for SHA1, cut to the six digits this package
+// uses.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCode(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", v.unix, err)
+		}
+		if got != v.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", v.unix, got, v.code)
+		}
+	}
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("TOTPCode accepted a malformed secret")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	for _, v := range rfcVectors {
+		step, ok := ValidateTOTP(strings.ToLower(rfcSecret), v.code[:3]+" "+v.code[3:], time.Unix(v.unix, 0))
+		if !ok || step != TOTPStep(time.Unix(v.unix, 0)) {
+			t.Errorf("ValidateTOTP(%d) = %d, %v; want %d, true", v.unix, step, ok, TOTPStep(time.Unix(v.unix, 0)))
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := TOTPStep(now)
+	tests := []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+	for _, tt := range tests {
+		code, err := TOTPCode(rfcSecret, step+tt.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := ValidateTOTP(rfcSecret, code, now)
+		if ok != tt.ok {
+			t.Errorf("offset %d: ok = %v, want %v", tt.offset, ok, tt.ok)
+			continue
+		}
+		// The matched step, not the current one, is returned so a code
+		// cannot be replayed later in the skew window.
+		if ok && got != step+tt.offset {
+			t.Errorf("offset %d: step = %d, want %d", tt.offset, got, step+tt.offset)
+		}
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "000000", "abcdef"} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now); ok {
+			t.Errorf("ValidateTOTP accepted %q", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "287082", now); ok {
+		t.Error("ValidateTOTP accepted a malformed secret")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 200 {
+		t.Fatalf("got %d codes, want 200", len(codes))
+	}
+	seen := map[string]bool{}
+	counts := map[rune]int{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' {
+			t.Fatalf("code %q is not of the form xxxxx-xxxxx", c)
+		}
+		if seen[c] {
+			t.Errorf("duplicate code %q", c)
+		}
+		seen[c] = true
+		if NormalizeRecoveryCode(" "+strings.ToUpper(c)+" ") != c {
+			t.Errorf("NormalizeRecoveryCode does not round-trip %q", c)
+		}
+		for _, r := range strings.ReplaceAll(c, "-", "") {
+			if !strings.ContainsRune(recoveryAlphabet, r) {
+				t.Fatalf("code %q has %q outside the alphabet", c, r)
+			}
+			counts[r]++
+		}
+	}
+	if len(counts) != len(recoveryAlphabet) {
+		t.Errorf("2000 symbols used %d of %d alphabet entries", len(counts), len(recoveryAlphabet))
+	}
+}
+
+func TestRecoveryByteLimit(t *testing.T) {
+	if recoveryByteLimit%len(recoveryAlphabet) != 0 || recoveryByteLimit > 256 || 256-recoveryByteLimit >= len(recoveryAlphabet) {
+		t.Errorf("recoveryByteLimit = %d is not the largest multiple of %d up to 256", recoveryByteLimit, len(recoveryAlphabet))
+	}
+}
@@ -0,0 +1,109 @@
+// Package geoip maps client IPs to country codes from a local CSV file of IP
+// ranges, one per line as either "cidr,CC" or "first_ip,last_ip,CC". Blank
+// lines and lines starting with # are ignored.
+package geoip
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+type ipRange struct {
+	first, last netip.Addr
+	country     string
+}
+
+type DB struct {
+	ranges []ipRange
+}
+
+func Load(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	db := &DB{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		db.ranges = append(db.ranges, r)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(db.ranges, func(i, j int) bool { return db.ranges[i].first.Less(db.ranges[j].first) })
+	return db, nil
+}
+
+func parseLine(line string) (ipRange, error) {
+	fields := strings.Split(line, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	switch len(fields) {
+	case 2:
+		p, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			return ipRange{}, err
+		}
+		p = p.Masked()
+		return ipRange{first: p.Addr(), last: lastAddr(p), country: strings.ToUpper(fields[1])}, nil
+	case 3:
+		first, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return ipRange{}, err
+		}
+		last, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return ipRange{}, err
+		}
+		if last.Less(first) || first.Is4() != last.Is4() {
+			return ipRange{}, fmt.Errorf("invalid range %s-%s", first, last)
+		}
+		return ipRange{first: first, last: last, country: strings.ToUpper(fields[2])}, nil
+	}
+	return ipRange{}, fmt.Errorf("expected 2 or 3 fields, got %d", len(fields))
+}
+
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
+
+// Country returns the country code for ip, or "" when no range covers it.
+// Ranges are expected not to overlap.
+func (db *DB) Country(ip string) string {
+	if db == nil {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	i := sort.Search(len(db.ranges), func(i int) bool { return addr.Less(db.ranges[i].first) })
+	if i == 0 {
+		return ""
+	}
+	r := db.ranges[i-1]
+	if r.first.Is4() != addr.Is4() || r.last.Less(addr) {
+		return ""
+	}
+	return r.country
+}
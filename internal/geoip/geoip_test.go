@@ -0,0 +1,84 @@
+package geoip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line, first, last, country string
+		wantErr                    bool
+	}{
+		{line: "203.0.113.0/24,lk", first: "203.0.113.0", last: "203.0.113.255", country: "LK"},
+		{line: "203.0.113.77/24, LK", first: "203.0.113.0", last: "203.0.113.255", country: "LK"},
+		{line: "198.51.100.10,198.51.100.20,IN", first: "198.51.100.10", last: "198.51.100.20", country: "IN"},
+		{line: "2001:db8::/32,LK", first: "2001:db8::", last: "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", country: "LK"},
+		{line: "198.51.100.20,198.51.100.10,IN", wantErr: true},
+		{line: "198.51.100.1,2001:db8::1,IN", wantErr: true},
+		{line: "not-an-ip/8,LK", wantErr: true},
+		{line: "LK", wantErr: true},
+		{line: "1.2.3.4,1.2.3.5,LK,extra", wantErr: true},
+	}
+	for _, tt := range tests {
+		r, err := parseLine(tt.line)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLine(%q): want error, got %v", tt.line, r)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseLine(%q): %v", tt.line, err)
+			continue
+		}
+		if r.first.String() != tt.first || r.last.String() != tt.last || r.country != tt.country {
+			t.Errorf("parseLine(%q) = %s-%s %s, want %s-%s %s", tt.line, r.first, r.last, r.country, tt.first, tt.last, tt.country)
+		}
+	}
+}
+
+func TestCountry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geo.csv")
+	data := "# test ranges\n\n198.51.100.0/24,IN\n203.0.113.0,203.0.113.127,LK\n10.0.0.0/8,US\n2001:db8::/32,GB\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ ip, want string }{
+		{"10.0.0.0", "US"},
+		{"10.255.255.255", "US"},
+		{"11.0.0.0", ""},
+		{"9.255.255.255", ""},
+		{"198.51.100.42", "IN"},
+		{"203.0.113.0", "LK"},
+		{"203.0.113.127", "LK"},
+		{"203.0.113.128", ""},
+		{"::ffff:203.0.113.5", "LK"},
+		{"2001:db8::1", "GB"},
+		{"2001:db9::1", ""},
+		{"garbage", ""},
+	}
+	for _, tt := range tests {
+		if got := db.Country(tt.ip); got != tt.want {
+			t.Errorf("Country(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+	if got := (*DB)(nil).Country("10.0.0.1"); got != "" {
+		t.Errorf("nil DB Country = %q, want empty", got)
+	}
+}
+
+func TestLoadReportsLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geo.csv")
+	if err := os.WriteFile(path, []byte("10.0.0.0/8,US\nbad line\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("want error for malformed line")
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go-next-cms/internal/i18n"
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/repo"
+	"go-next-cms/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type API struct {
+	Repo    *repo.Repository
+	Service *service.Service
+	I18n    *i18n.Bundle
+}
+
+func New(r *repo.Repository, s *service.Service, b *i18n.Bundle) *API {
+	return &API{Repo: r, Service: s, I18n: b}
+}
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ErrorEnvelope struct {
+	Error ErrorBody `json:"error"`
+}
+
+type Meta struct {
+	Lang       string      `json:"lang,omitempty"`
+	Total      int         `json:"total,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Facets     *FacetsJSON `json:"facets,omitempty"`
+}
+
+type Envelope[T any] struct {
+	Data T     `json:"data"`
+	Meta *Meta `json:"meta,omitempty"`
+}
+
+func ok[T any](c *fiber.Ctx, data T, meta *Meta) error {
+	return c.JSON(Envelope[T]{Data: data, Meta: meta})
+}
+
+func fail(c *fiber.Ctx, status int, code, msg string) error {
+	return c.Status(status).JSON(ErrorEnvelope{Error: ErrorBody{Code: code, Message: msg}})
+}
+
+// Error renders errors that escape API handlers, such as unmatched routes,
+// in the JSON error envelope.
+func (a *API) Error(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) && fe.Code < 500 {
+		return fail(c, fe.Code, strings.ToLower(strings.ReplaceAll(utils.StatusMessage(fe.Code), " ", "_")), fe.Message)
+	}
+	return failErr(c, err)
+}
+
+func failErr(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return fail(c, fiber.StatusNotFound, "not_found", "resource not found")
+	case errors.Is(err, service.ErrTransitionNotAllowed):
+		return fail(c, fiber.StatusConflict, "transition_not_allowed", err.Error())
+	case errors.Is(err, service.ErrInvalidTransition):
+		return fail(c, fiber.StatusConflict, "invalid_transition", err.Error())
+	}
+	log.Printf("api %s %s: %v", c.Method(), c.Path(), err)
+	return fail(c, fiber.StatusInternalServerError, "internal", "internal server error")
+}
+
+// lang picks the best Accept-Language match among the bundle languages and
+// echoes it back in Content-Language.
+func (a *API) lang(c *fiber.Ctx) string {
+	lang := i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage), a.I18n.Languages())
+	if lang == "" {
+		lang = "en"
+	}
+	c.Set(fiber.HeaderContentLanguage, lang)
+	c.Vary(fiber.HeaderAcceptLanguage)
+	return lang
+}
+
+// langs is the content fallback chain of the negotiated language, with the
+// fallbacks of country when there is one.
+func (a *API) langs(c *fiber.Ctx, country *models.Country) []string {
+	var fb i18n.Fallbacks
+	if country != nil {
+		fb = country.FallbackChains
+	}
+	return a.I18n.Chain(a.lang(c), fb)
+}
+
+// cursor is the keyset position of the last deal on a page, with the page
+// size to keep.
+type cursor struct {
+	EndAt time.Time `json:"e"`
+	ID    int64     `json:"i"`
+	Size  int       `json:"s"`
+}
+
+func encodeCursor(cur cursor) string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var cur cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, err
+	}
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return cur, err
+	}
+	if cur.ID < 1 || cur.EndAt.IsZero() || cur.Size < 1 || cur.Size > maxLimit {
+		return cur, errors.New("cursor out of range")
+	}
+	return cur, nil
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"go-next-cms/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (a *API) Countries(c *fiber.Ctx) error {
+	items, err := a.Repo.Countries(c.Context())
+	if err != nil {
+		return failErr(c, err)
+	}
+	out := make([]CountryJSON, 0, len(items))
+	for _, x := range items {
+		out = append(out, CountryJSON{Code: x.Code, Name: x.Name, DefaultLanguage: x.DefaultLanguage, Languages: x.Languages})
+	}
+	return ok(c, out, nil)
+}
+
+func (a *API) Cities(c *fiber.Ctx) error {
+	country, err := a.Service.Country(c.Context(), c.Params("countryCode"))
+	if err != nil {
+		return failErr(c, err)
+	}
+	items, err := a.Repo.CitiesByCountry(c.Context(), country.ID, a.langs(c, country))
+	if err != nil {
+		return failErr(c, err)
+	}
+	out := make([]CityJSON, 0, len(items))
+	for _, x := range items {
+		out = append(out, CityJSON{ID: x.ID, Name: x.Name, Slug: x.Slug})
+	}
+	return ok(c, out, nil)
+}
+
+func (a *API) Categories(c *fiber.Ctx) error {
+	items, err := a.Repo.Categories(c.Context(), a.langs(c, nil))
+	if err != nil {
+		return failErr(c, err)
+	}
+	out := make([]CategoryJSON, 0, len(items))
+	for _, x := range items {
+		out = append(out, CategoryJSON{ID: x.ID, Name: x.Name, Slug: x.Slug})
+	}
+	return ok(c, out, nil)
+}
+
+func (a *API) Merchants(c *fiber.Ctx) error {
+	items, err := a.Repo.Merchants(c.Context(), a.langs(c, nil))
+	if err != nil {
+		return failErr(c, err)
+	}
+	out := make([]MerchantJSON, 0, len(items))
+	for _, x := range items {
+		out = append(out, MerchantJSON{ID: x.ID, Name: x.Name, Slug: x.Slug, LogoURL: x.LogoURL, Verified: x.Verified})
+	}
+	return ok(c, out, nil)
+}
+
+func (a *API) DealTypes(c *fiber.Ctx) error {
+	items, err := a.Repo.DealTypes(c.Context(), a.langs(c, nil))
+	if err != nil {
+		return failErr(c, err)
+	}
+	out := make([]DealTypeJSON, 0, len(items))
+	for _, x := range items {
+		out = append(out, DealTypeJSON{ID: x.ID, Code: x.Code, Name: x.Name})
+	}
+	return ok(c, out, nil)
+}
+
+func (a *API) Deals(c *fiber.Ctx) error {
+	var f models.DealFilter
+	if err := c.QueryParser(&f); err != nil {
+		return fail(c, fiber.StatusBadRequest, "invalid_query", err.Error())
+	}
+	country, err := a.Service.Country(c.Context(), c.Params("countryCode"))
+	if err != nil {
+		return failErr(c, err)
+	}
+	f.CountryCode = country.Code
+	f.Langs = a.langs(c, country)
+	size := c.QueryInt("limit", defaultLimit)
+	if raw := c.Query("cursor"); raw != "" {
+		cur, err := decodeCursor(raw)
+		if err != nil {
+			return fail(c, fiber.StatusBadRequest, "invalid_cursor", err.Error())
+		}
+		f.After, size = &models.DealKey{EndAt: cur.EndAt, ID: cur.ID}, cur.Size
+	}
+	if size < 1 || size > maxLimit {
+		return fail(c, fiber.StatusBadRequest, "invalid_limit", "limit must be between 1 and 100")
+	}
+	f.Keyset, f.PageSize = true, size
+	res, err := a.Repo.ListDeals(c.Context(), f)
+	if err != nil {
+		return failErr(c, err)
+	}
+	out := make([]DealJSON, 0, len(res.Deals))
+	for _, d := range res.Deals {
+		out = append(out, dealJSON(d))
+	}
+	meta := &Meta{Lang: a.lang(c), Total: res.Total, Facets: facetsJSON(res.Facets)}
+	if res.More && len(res.Deals) > 0 {
+		last := res.Deals[len(res.Deals)-1]
+		meta.NextCursor = encodeCursor(cursor{EndAt: last.EndAt, ID: last.ID, Size: size})
+	}
+	return ok(c, out, meta)
+}
+
+func (a *API) Deal(c *fiber.Ctx) error {
+	country, err := a.Service.Country(c.Context(), c.Params("countryCode"))
+	if err != nil {
+		return failErr(c, err)
+	}
+	d, err := a.Repo.DealBySlug(c.Context(), country.Code, c.Params("dealSlug"), a.langs(c, country))
+	if err != nil {
+		return failErr(c, err)
+	}
+	if d.Status != models.DealPublished {
+		return fail(c, fiber.StatusNotFound, "not_found", "resource not found")
+	}
+	trs, err := a.Repo.DealTranslations(c.Context(), d.ID)
+	if err != nil {
+		return failErr(c, err)
+	}
+	out := DealDetailJSON{DealJSON: dealJSON(*d), Translations: make([]TranslationJSON, 0, len(trs))}
+	for _, t := range trs {
+		if a.Repo.HideUnreviewedTranslations && t.Status != models.TranslationReviewed {
+			continue
+		}
+		out.Translations = append(out.Translations, TranslationJSON{Lang: t.Lang, Title: t.Title, Description: t.Description, Status: string(t.Status)})
+	}
+	return ok(c, out, &Meta{Lang: a.lang(c)})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"time"
+
+	"go-next-cms/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ModerationRequest struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+func (a *API) ModerateDeal(c *fiber.Ctx) error {
+	// Writes require a bearer token: session cookies carry no CSRF protection here.
+	u, authed := c.Locals("user").(*models.User)
+	if _, hasToken := c.Locals("api_token").(*models.APIToken); !authed || !hasToken {
+		return fail(c, fiber.StatusUnauthorized, "unauthenticated", "API token required")
+	}
+	if !u.Can(models.PermModerate) {
+		return fail(c, fiber.StatusForbidden, "forbidden", "moderate permission required")
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fail(c, fiber.StatusBadRequest, "invalid_id", "deal id must be numeric")
+	}
+	var req ModerationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fail(c, fiber.StatusBadRequest, "invalid_body", err.Error())
+	}
+	d, err := a.Repo.DealByID(c.Context(), int64(id))
+	if err != nil {
+		return failErr(c, err)
+	}
+	if !u.InCountry(d.CountryID) {
+		return fail(c, fiber.StatusForbidden, "forbidden", "deal is outside your countries")
+	}
+	switch req.Action {
+	case "approve":
+		steps := []models.DealStatus{models.DealApproved}
+		if !d.StartAt.After(time.Now()) {
+			steps = append(steps, models.DealPublished)
+		}
+		err = a.Service.TransitionDeal(c.Context(), d.ID, u, nil, steps...)
+	case "reject":
+		err = a.Service.TransitionDeal(c.Context(), d.ID, u, &req.Reason, models.DealRejected)
+	default:
+		return fail(c, fiber.StatusBadRequest, "invalid_action", "action must be approve or reject")
+	}
+	if err != nil {
+		return failErr(c, err)
+	}
+	d, err = a.Repo.DealByID(c.Context(), d.ID)
+	if err != nil {
+		return failErr(c, err)
+	}
+	return ok(c, map[string]models.DealStatus{"status": d.Status}, nil)
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const BasePath = "/api/v1"
+
+var (
+	specOnce sync.Once
+	spec     map[string]any
+)
+
+func (a *API) OpenAPI(c *fiber.Ctx) error {
+	specOnce.Do(func() { spec = BuildSpec() })
+	return c.JSON(spec)
+}
+
+var pathParamRe = regexp.MustCompile(`:(\w+)`)
+
+// SpecPath converts a Fiber route path into its OpenAPI template form.
+func SpecPath(path string) string { return pathParamRe.ReplaceAllString(path, "{$1}") }
+
+// BuildSpec derives an OpenAPI 3.1 document from Operations, reflecting the
+// Go request and response types into component schemas.
+func BuildSpec() map[string]any {
+	g := &schemaGen{components: map[string]any{}}
+	paths := map[string]any{}
+	for _, op := range Operations {
+		p := SpecPath(op.Path)
+		item, _ := paths[p].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[p] = item
+		}
+		item[strings.ToLower(op.Method)] = g.operation(op)
+	}
+	g.components["Error"] = g.schema(reflect.TypeOf(ErrorEnvelope{}))
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info":    map[string]any{"title": "Deals API", "version": "1.0.0"},
+		"servers": []any{map[string]any{"url": BasePath}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas":         g.components,
+			"securitySchemes": map[string]any{"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"}},
+		},
+	}
+}
+
+type schemaGen struct{ components map[string]any }
+
+func (g *schemaGen) operation(op Operation) map[string]any {
+	params := []any{}
+	for _, m := range pathParamRe.FindAllStringSubmatch(op.Path, -1) {
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+	if op.Query != nil {
+		t := reflect.TypeOf(op.Query)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := f.Tag.Get("query")
+			if name == "" || name == "-" {
+				continue
+			}
+			params = append(params, map[string]any{"name": name, "in": "query", "schema": g.schema(f.Type)})
+		}
+	}
+	for _, p := range op.Params {
+		params = append(params, map[string]any{"name": p.Name, "in": "query", "description": p.Description, "schema": g.schema(reflect.TypeOf(p.Type))})
+	}
+	out := map[string]any{
+		"summary":    op.Summary,
+		"parameters": params,
+		"responses": map[string]any{
+			"200":     map[string]any{"description": "OK", "content": jsonContent(g.schema(reflect.TypeOf(op.Response)))},
+			"default": map[string]any{"description": "Error", "content": jsonContent(map[string]any{"$ref": "#/components/schemas/Error"})},
+		},
+	}
+	if op.Scope != "" {
+		out["security"] = []any{map[string]any{"bearerAuth": []string{string(op.Scope)}}, map[string]any{}}
+	}
+	if op.Request != nil {
+		out["requestBody"] = map[string]any{"required": true, "content": jsonContent(g.schema(reflect.TypeOf(op.Request)))}
+	}
+	return out
+}
+
+func jsonContent(schema any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		s := g.schema(t.Elem())
+		if typ, ok := s["type"].(string); ok {
+			s["type"] = []string{typ, "null"}
+			return s
+		}
+		return map[string]any{"oneOf": []any{s, map[string]any{"type": "null"}}}
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		// Generic instantiations such as Envelope[T] have no stable
+		// component name, so they are inlined.
+		if t.Name() == "" || strings.Contains(t.Name(), "[") {
+			return g.object(t)
+		}
+		if _, ok := g.components[t.Name()]; !ok {
+			g.components[t.Name()] = map[string]any{}
+			g.components[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	g.fields(t, props, &required)
+	out := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+func (g *schemaGen) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.fields(f.Type, props, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
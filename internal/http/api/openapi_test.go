@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestEveryRouteHasSpecEntry(t *testing.T) {
+	app := fiber.New()
+	New(nil, nil, nil).Routes(app.Group(BasePath))
+
+	paths := BuildSpec()["paths"].(map[string]any)
+	seen := 0
+	for _, r := range app.GetRoutes(true) {
+		if r.Method == fiber.MethodHead || !strings.HasPrefix(r.Path, BasePath+"/") {
+			continue
+		}
+		seen++
+		p := SpecPath(strings.TrimPrefix(r.Path, BasePath))
+		item, ok := paths[p].(map[string]any)
+		if !ok {
+			t.Errorf("%s %s: no OpenAPI path %s", r.Method, r.Path, p)
+			continue
+		}
+		if _, ok := item[strings.ToLower(r.Method)]; !ok {
+			t.Errorf("%s %s: no OpenAPI operation", r.Method, r.Path)
+		}
+	}
+	if seen == 0 {
+		t.Fatal("no API routes registered")
+	}
+}
+
+func TestSpecMarshalsWithSchemas(t *testing.T) {
+	b, err := json.Marshal(BuildSpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"openapi":"3.1.0"`, `"DealJSON"`, `"name":"ending_soon"`, `"name":"countryCode"`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("spec missing %s", want)
+		}
+	}
+}
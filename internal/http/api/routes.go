@@ -0,0 +1,53 @@
+package api
+
+import (
+	"go-next-cms/internal/http/middleware"
+	"go-next-cms/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Routes mounts the v1 endpoints on r. Every route added here needs a
+// matching entry in Operations so it shows up in /api/openapi.json.
+func (a *API) Routes(r fiber.Router) {
+	read := middleware.RequireScope(models.ScopeDealsRead)
+	r.Get("/countries", read, a.Countries)
+	r.Get("/countries/:countryCode/cities", read, a.Cities)
+	r.Get("/countries/:countryCode/deals", read, a.Deals)
+	r.Get("/countries/:countryCode/deals/:dealSlug", read, a.Deal)
+	r.Get("/categories", read, a.Categories)
+	r.Get("/merchants", read, a.Merchants)
+	r.Get("/deal-types", read, a.DealTypes)
+	r.Post("/deals/:id/moderation", middleware.RequireScope(models.ScopeModeration), a.ModerateDeal)
+}
+
+type Param struct {
+	Name        string
+	Description string
+	Type        any
+}
+
+type Operation struct {
+	Method   string
+	Path     string
+	Summary  string
+	Scope    models.APIScope
+	Query    any
+	Params   []Param
+	Request  any
+	Response any
+}
+
+var Operations = []Operation{
+	{Method: fiber.MethodGet, Path: "/countries", Summary: "List countries", Scope: models.ScopeDealsRead, Response: Envelope[[]CountryJSON]{}},
+	{Method: fiber.MethodGet, Path: "/countries/:countryCode/cities", Summary: "List cities of a country", Scope: models.ScopeDealsRead, Response: Envelope[[]CityJSON]{}},
+	{Method: fiber.MethodGet, Path: "/countries/:countryCode/deals", Summary: "List published deals", Scope: models.ScopeDealsRead, Query: models.DealFilter{}, Params: []Param{
+		{Name: "limit", Description: "Page size (1-100, default 20)", Type: 0},
+		{Name: "cursor", Description: "Opaque cursor from meta.next_cursor", Type: ""},
+	}, Response: Envelope[[]DealJSON]{}},
+	{Method: fiber.MethodGet, Path: "/countries/:countryCode/deals/:dealSlug", Summary: "Get a published deal with its translations", Scope: models.ScopeDealsRead, Response: Envelope[DealDetailJSON]{}},
+	{Method: fiber.MethodGet, Path: "/categories", Summary: "List categories", Scope: models.ScopeDealsRead, Response: Envelope[[]CategoryJSON]{}},
+	{Method: fiber.MethodGet, Path: "/merchants", Summary: "List merchants", Scope: models.ScopeDealsRead, Response: Envelope[[]MerchantJSON]{}},
+	{Method: fiber.MethodGet, Path: "/deal-types", Summary: "List deal types", Scope: models.ScopeDealsRead, Response: Envelope[[]DealTypeJSON]{}},
+	{Method: fiber.MethodPost, Path: "/deals/:id/moderation", Summary: "Approve or reject a pending deal", Scope: models.ScopeModeration, Request: ModerationRequest{}, Response: Envelope[map[string]models.DealStatus]{}},
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"time"
+
+	"go-next-cms/internal/models"
+)
+
+type CountryJSON struct {
+	Code            string   `json:"code"`
+	Name            string   `json:"name"`
+	DefaultLanguage string   `json:"default_language"`
+	Languages       []string `json:"languages"`
+}
+
+type CityJSON struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type CategoryJSON struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type MerchantJSON struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	LogoURL  string `json:"logo_url,omitempty"`
+	Verified bool   `json:"verified"`
+}
+
+type DealTypeJSON struct {
+	ID   int64  `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type DealJSON struct {
+	ID          int64         `json:"id"`
+	Slug        string        `json:"slug"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	CountryCode string        `json:"country_code"`
+	City        CityJSON      `json:"city"`
+	Category    CategoryJSON  `json:"category"`
+	Merchant    *MerchantJSON `json:"merchant,omitempty"`
+	DealType    DealTypeJSON  `json:"deal_type"`
+	StartAt     time.Time     `json:"start_at"`
+	EndAt       time.Time     `json:"end_at"`
+	Featured    bool          `json:"featured"`
+	ImageURL    string        `json:"image_url,omitempty"`
+}
+
+type TranslationJSON struct {
+	Lang        string `json:"lang"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+type DealDetailJSON struct {
+	DealJSON
+	Translations []TranslationJSON `json:"translations"`
+}
+
+type FacetJSON struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+type FacetsJSON struct {
+	Cities     []FacetJSON `json:"cities"`
+	Categories []FacetJSON `json:"categories"`
+	DealTypes  []FacetJSON `json:"deal_types"`
+	Merchants  []FacetJSON `json:"merchants"`
+	EndingSoon int         `json:"ending_soon"`
+}
+
+// dealJSON converts a deal whose text the repository already resolved to the
+// request's language chain.
+func dealJSON(d models.Deal) DealJSON {
+	out := DealJSON{
+		ID:          d.ID,
+		Slug:        d.Slug,
+		Title:       d.Title,
+		Description: d.Description,
+		CountryCode: d.CountryCode,
+		City:        CityJSON{ID: d.CityID, Name: d.CityName},
+		Category:    CategoryJSON{ID: d.CategoryID, Name: d.CategoryName, Slug: d.CategorySlug},
+		DealType:    DealTypeJSON{ID: d.DealTypeID, Name: d.DealTypeName},
+		StartAt:     d.StartAt,
+		EndAt:       d.EndAt,
+		Featured:    d.Featured,
+		ImageURL:    d.ImageURL,
+	}
+	if d.MerchantID != nil && d.MerchantName != nil {
+		out.Merchant = &MerchantJSON{ID: *d.MerchantID, Name: *d.MerchantName}
+	}
+	return out
+}
+
+func facetsJSON(f models.DealFacets) *FacetsJSON {
+	conv := func(in []models.FacetCount) []FacetJSON {
+		out := make([]FacetJSON, 0, len(in))
+		for _, fc := range in {
+			out = append(out, FacetJSON{Value: fc.Value, Label: fc.Label, Count: fc.Count})
+		}
+		return out
+	}
+	return &FacetsJSON{Cities: conv(f.Cities), Categories: conv(f.Categories), DealTypes: conv(f.DealTypes), Merchants: conv(f.Merchants), EndingSoon: f.EndingSoon}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// accountMessage renders a page with a heading and a single message.
+func (h *Handler) accountMessage(c *fiber.Ctx, title, msg string) error {
+	return h.render(c, title, h.lastCountry(c), template.HTML(fmt.Sprintf("<h1>%s</h1><p>%s</p>", title, msg)))
+}
+
+func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
+	t := h.t(c)
+	if token := c.Query("token"); token != "" {
+		if err := h.Service.VerifyEmail(c.Context(), token); err != nil {
+			if errors.Is(err, service.ErrInvalidToken) {
+				return h.accountMessage(c, t("verify_email"), t("error_invalid_token"))
+			}
+			return err
+		}
+		return h.accountMessage(c, t("verify_email"), t("email_confirmed"))
+	}
+	u, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Redirect("/account/login")
+	}
+	if u.EmailVerifiedAt != nil {
+		return h.accountMessage(c, t("verify_email"), t("email_confirmed"))
+	}
+	body := fmt.Sprintf("<h1>%s</h1><p>%s</p><form method='post' action='/account/verify'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", t("verify_email"), t("verify_email_prompt", "email", template.HTMLEscapeString(u.Email)), c.Locals("csrf"), t("resend_link"))
+	return h.render(c, t("verify_email"), h.lastCountry(c), template.HTML(body))
+}
+
+func (h *Handler) ResendVerification(c *fiber.Ctx) error {
+	u := c.Locals("user").(*models.User)
+	if u.EmailVerifiedAt == nil {
+		if err := h.Service.SendVerification(c.Context(), u); err != nil {
+			return err
+		}
+	}
+	t := h.t(c)
+	return h.accountMessage(c, t("verify_email"), t("verification_resent"))
+}
+
+func (h *Handler) ResetForm(c *fiber.Ctx) error {
+	csrf := c.Locals("csrf").(string)
+	t := h.t(c)
+	if token := c.Query("token"); token != "" {
+		body := fmt.Sprintf("<h1>%s</h1><form method='post' action='/account/reset'><input name='password' type='password' minlength='%d'><input type='hidden' name='token' value='%s'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", t("choose_password"), service.MinPasswordLen, template.HTMLEscapeString(token), csrf, t("save_password"))
+		return h.render(c, t("reset_password"), h.lastCountry(c), template.HTML(body))
+	}
+	body := fmt.Sprintf("<h1>%s</h1><form method='post' action='/account/reset'><input name='email' type='email'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", t("reset_password"), csrf, t("send_reset_link"))
+	return h.render(c, t("reset_password"), h.lastCountry(c), template.HTML(body))
+}
+
+func (h *Handler) ResetPassword(c *fiber.Ctx) error {
+	token := c.FormValue("token")
+	if token == "" {
+		h.Service.RequestPasswordReset(c.FormValue("email"))
+		t := h.t(c)
+		return h.accountMessage(c, t("reset_password"), t("reset_sent"))
+	}
+	if len(c.FormValue("password")) < service.MinPasswordLen {
+		return userError(400, h.t(c)("error_password_length", "min", service.MinPasswordLen))
+	}
+	if err := h.Service.ResetPassword(c.Context(), token, c.FormValue("password")); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			return userError(400, h.t(c)("error_invalid_token"))
+		}
+		return err
+	}
+	return c.Redirect("/account/login")
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const countryCookie = "country"
+
+// country returns the code of a /:countryCode page, whose country
+// middleware.KnownCountry has loaded, and remembers it as the visitor's last
+// country.
+func (h *Handler) country(c *fiber.Ctx) string {
+	cc := c.Locals("country").(*models.Country).Code
+	if c.Cookies(countryCookie) != cc {
+		c.Cookie(&fiber.Cookie{Name: countryCookie, Value: cc, Path: "/", SameSite: "Lax"})
+	}
+	return cc
+}
+
+// lastCountry is the country used by pages outside /:countryCode: an explicit
+// ?country=, else the last country visited, else the configured default.
+func (h *Handler) lastCountry(c *fiber.Ctx) string {
+	if cc := h.chosenCountry(c); cc != "" {
+		return cc
+	}
+	return h.DefaultCountry
+}
+
+// chosenCountry is the code of ?country=, else of the last country visited,
+// when that country exists, else "".
+func (h *Handler) chosenCountry(c *fiber.Ctx) string {
+	for _, cc := range []string{c.Query("country"), c.Cookies(countryCookie)} {
+		if cc = strings.ToUpper(cc); !service.ValidCountryCode(cc) {
+			continue
+		}
+		if _, err := h.Service.Country(c.Context(), cc); err == nil {
+			return cc
+		}
+	}
+	return ""
+}
+
+// currentCountry is the country of a /:countryCode page, else the last
+// country, or nil when that is unknown.
+func (h *Handler) currentCountry(c *fiber.Ctx) *models.Country {
+	if co, ok := c.Locals("country").(*models.Country); ok {
+		return co
+	}
+	co, err := h.Service.Country(c.Context(), h.lastCountry(c))
+	if err != nil {
+		return nil
+	}
+	return co
+}
+
+// Root sends visitors to their last country, else the country their IP maps
+// to in the GeoIP file when we serve it, else the default country.
+func (h *Handler) Root(c *fiber.Ctx) error {
+	if cc := h.chosenCountry(c); cc != "" {
+		return c.Redirect("/" + cc)
+	}
+	if cc := h.GeoIP.Country(c.IP()); cc != "" {
+		if _, err := h.Service.Country(c.Context(), cc); err == nil {
+			return c.Redirect("/" + cc)
+		}
+	}
+	return c.Redirect("/" + h.DefaultCountry)
+}
+
+// CityOptions renders the <option>s for a country's cities; the country
+// picker on deal forms swaps them into the city select.
+func (h *Handler) CityOptions(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Query("country_id"), 10, 64)
+	cities, err := h.Repo.CitiesByCountry(c.Context(), id, h.langs(c))
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, x := range cities {
+		fmt.Fprintf(&b, "<option value='%d'>%s</option>", x.ID, template.HTMLEscapeString(x.Name))
+	}
+	return c.Type("html").SendString(b.String())
+}
+
+// countryPicker renders the country and city selects of a deal form, with
+// the submitted country and city, else the current country, preselected when
+// among countries. It also returns the preselected country.
+func (h *Handler) countryPicker(c *fiber.Ctx, countries []models.Country) (string, *models.Country) {
+	if len(countries) == 0 {
+		return "", nil
+	}
+	selected := countries[0]
+	posted := formValue(c, "country_id", "")
+	for _, co := range countries {
+		if posted == "" && co.Code == h.lastCountry(c) || posted == strconv.FormatInt(co.ID, 10) {
+			selected = co
+		}
+	}
+	var countryOpts, cityOpts strings.Builder
+	for _, co := range countries {
+		sel := ""
+		if co.ID == selected.ID {
+			sel = " selected"
+		}
+		fmt.Fprintf(&countryOpts, "<option value='%d'%s>%s</option>", co.ID, sel, template.HTMLEscapeString(co.Name))
+	}
+	cities, _ := h.Repo.CitiesByCountry(c.Context(), selected.ID, h.langs(c))
+	city := formValue(c, "city_id", "")
+	for _, x := range cities {
+		fmt.Fprintf(&cityOpts, "<option value='%d'%s>%s</option>", x.ID, selectedIf(city == strconv.FormatInt(x.ID, 10)), template.HTMLEscapeString(x.Name))
+	}
+	return fmt.Sprintf("<select name='country_id' hx-get='/account/cities' hx-target='#city_id'>%s</select><select name='city_id' id='city_id'>%s</select>", countryOpts.String(), cityOpts.String()), &selected
+}
+
+// adminCountryFilter narrows the user's country scope to ?country= when it is
+// one of theirs. It returns the country IDs to filter by (nil for all, empty
+// for none) and a bar of filter links for path.
+func (h *Handler) adminCountryFilter(c *fiber.Ctx, path string) ([]int64, template.HTML, error) {
+	u := c.Locals("user").(*models.User)
+	var ids []int64
+	if !u.AllCountries {
+		ids = append([]int64{}, u.CountryIDs...)
+	}
+	countries, err := h.scopedCountries(c)
+	if err != nil {
+		return nil, "", err
+	}
+	selected := strings.ToUpper(c.Query("country"))
+	var b strings.Builder
+	fmt.Fprintf(&b, "<nav class='country-filter'><a href='%s'>%s</a>", path, h.t(c)("all_countries"))
+	for _, co := range countries {
+		if co.Code == selected {
+			ids = []int64{co.ID}
+			fmt.Fprintf(&b, " | <strong>%s</strong>", template.HTMLEscapeString(co.Name))
+			continue
+		}
+		fmt.Fprintf(&b, " | <a href='%s?country=%s'>%s</a>", path, co.Code, template.HTMLEscapeString(co.Name))
+	}
+	b.WriteString("</nav>")
+	return ids, template.HTML(b.String()), nil
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// errorPages are the statuses with their own localized page; other 4xx and
+// 5xx statuses fall back to 400 and 500.
+//
+//i18n:keys error_400_title error_400_body error_403_title error_403_body error_404_title error_404_body
+//i18n:keys error_429_title error_429_body error_500_title error_500_body
+var errorPages = map[int]bool{400: true, 403: true, 404: true, 429: true, 500: true}
+
+// shownError is a 4xx error whose message handlers wrote for the visitor.
+type shownError struct{ err *fiber.Error }
+
+func (e shownError) Error() string { return e.err.Message }
+func (e shownError) Unwrap() error { return e.err }
+
+// userError returns a 4xx error whose message, already localized, Error shows
+// on the page. Messages of other errors, from fiber or from lower layers, are
+// never shown.
+func userError(code int, msg string) error {
+	return shownError{fiber.NewError(code, msg)}
+}
+
+// Error is the app's fiber.ErrorHandler. It renders a localized error page in
+// the layout, and shows the request ID on 5xx pages so users can quote it.
+func (h *Handler) Error(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	detail := ""
+	var fe *fiber.Error
+	var shown shownError
+	switch {
+	case errors.As(err, &fe):
+		code = fe.Code
+		if errors.As(err, &shown) && code < 500 {
+			detail = fe.Message
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		code = fiber.StatusNotFound
+	}
+	rid, _ := c.Locals("requestid").(string)
+	if code >= 500 {
+		log.Printf("request %s %s %s: %v", rid, c.Method(), c.Path(), err)
+	}
+	page := code
+	if !errorPages[page] {
+		page = 400
+		if code >= 500 {
+			page = 500
+		}
+	}
+	t := h.t(c)
+	key := "error_" + strconv.Itoa(page)
+	body := fmt.Sprintf("<h1>%s</h1><p>%s</p>", t(key+"_title"), t(key+"_body"))
+	if detail != "" {
+		body += "<p>" + template.HTMLEscapeString(detail) + "</p>"
+	}
+	if code >= 500 && rid != "" {
+		body += fmt.Sprintf("<p>%s: <code>%s</code></p>", t("error_reference"), template.HTMLEscapeString(rid))
+	}
+	body += fmt.Sprintf("<p><a href='/%s'>%s</a></p>", h.lastCountry(c), t("back_home"))
+	c.Status(code)
+	if c.Get("HX-Request") == "true" {
+		return c.Type("html").SendString(body)
+	}
+	if rerr := h.render(c, t(key+"_title"), h.lastCountry(c), template.HTML(body)); rerr != nil {
+		return c.Status(code).SendString(utils.StatusMessage(code))
+	}
+	return nil
+}
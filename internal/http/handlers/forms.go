@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-next-cms/internal/i18n"
+	"go-next-cms/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// formValue is the submitted value of name when a rejected form is shown
+// again, else fallback.
+func formValue(c *fiber.Ctx, name, fallback string) string {
+	if c.Method() == fiber.MethodPost {
+		return c.FormValue(name)
+	}
+	return fallback
+}
+
+func selectedIf(ok bool) string {
+	if ok {
+		return " selected"
+	}
+	return ""
+}
+
+// fieldErrors renders the messages of field's errors in verr.
+//
+//i18n:keys error_required error_invalid_choice error_invalid_date error_invalid_slug error_taken error_title_length
+//i18n:keys error_end_before_start error_invalid_email error_unknown_city error_city_country error_invalid_country
+//i18n:keys error_password_length error_image
+func fieldErrors(t i18n.Func, verr *service.ValidationError, field string) string {
+	var b strings.Builder
+	for _, f := range verr.For(field) {
+		fmt.Fprintf(&b, "<p class='field-error'>%s</p>", t(f.Key, f.Args...))
+	}
+	return b.String()
+}
+
+// invalidForm reports whether err rejects a form's input, in which case the
+// form is shown again with the errors and a 422 status.
+func invalidForm(c *fiber.Ctx, err error) (*service.ValidationError, bool) {
+	var verr *service.ValidationError
+	if !errors.As(err, &verr) {
+		return nil, false
+	}
+	c.Status(fiber.StatusUnprocessableEntity)
+	return verr, true
+}
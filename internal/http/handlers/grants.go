@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// scopedCountries lists the countries the current user is assigned to.
+func (h *Handler) scopedCountries(c *fiber.Ctx) ([]models.Country, error) {
+	u := c.Locals("user").(*models.User)
+	countries, err := h.Repo.Countries(c.Context())
+	if err != nil {
+		return nil, err
+	}
+	out := countries[:0]
+	for _, co := range countries {
+		if u.InCountry(co.ID) {
+			out = append(out, co)
+		}
+	}
+	return out, nil
+}
+
+// managedUser loads the user of the :id parameter, who must be inside the
+// current user's country scope.
+func (h *Handler) managedUser(c *fiber.Ctx) (*models.User, error) {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	target, err := h.Repo.UserByID(c.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fiber.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !c.Locals("user").(*models.User).Manages(target) {
+		return nil, userError(fiber.StatusForbidden, h.t(c)("error_outside_scope"))
+	}
+	return target, nil
+}
+
+func (h *Handler) AdminUserCountries(c *fiber.Ctx) error {
+	target, err := h.managedUser(c)
+	if err != nil {
+		return err
+	}
+	countries, err := h.scopedCountries(c)
+	if err != nil {
+		return err
+	}
+	t := h.t(c)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>%s</h1><p>%s</p><form method='post'>", t("user_countries", "email", template.HTMLEscapeString(target.Email)), t("user_countries_help"))
+	if c.Locals("user").(*models.User).AllCountries {
+		checked := ""
+		if target.AllCountries {
+			checked = " checked"
+		}
+		fmt.Fprintf(&b, "<label><input type='checkbox' name='all_countries' value='1'%s> %s</label>", checked, t("all_countries"))
+	}
+	for _, co := range countries {
+		checked := ""
+		for _, g := range target.CountryIDs {
+			if g == co.ID {
+				checked = " checked"
+			}
+		}
+		fmt.Fprintf(&b, "<label><input type='checkbox' name='country_id' value='%d'%s> %s</label>", co.ID, checked, template.HTMLEscapeString(co.Name))
+	}
+	fmt.Fprintf(&b, "<input type='hidden' name='csrf' value='%s'><button>%s</button></form>", c.Locals("csrf"), t("save"))
+	return h.render(c, t("countries"), h.lastCountry(c), template.HTML(b.String()))
+}
+
+func (h *Handler) SaveUserCountries(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var ids []int64
+	for _, raw := range c.Context().PostArgs().PeekMulti("country_id") {
+		cid, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil {
+			return userError(400, h.t(c)("error_invalid_country"))
+		}
+		ids = append(ids, cid)
+	}
+	if err := h.Service.GrantCountries(c.Context(), c.Locals("user").(*models.User), id, c.FormValue("all_countries") == "1", ids); err != nil {
+		switch {
+		case errors.Is(err, service.ErrOutsideScope):
+			return userError(403, h.t(c)("error_outside_scope"))
+		case errors.Is(err, pgx.ErrNoRows):
+			return fiber.ErrNotFound
+		}
+		return err
+	}
+	return c.Redirect("/admin/users")
+}
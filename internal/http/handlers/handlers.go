@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
+	"log"
 	"strconv"
 	"strings"
+	"time"
 
-	"go-next-cms/internal/auth"
+	"go-next-cms/internal/geoip"
+	"go-next-cms/internal/http/middleware"
 	"go-next-cms/internal/i18n"
 	"go-next-cms/internal/models"
 	"go-next-cms/internal/repo"
@@ -16,6 +20,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
+	"github.com/jackc/pgx/v5"
 )
 
 type Handler struct {
@@ -24,26 +29,87 @@ type Handler struct {
 	I18n     *i18n.Bundle
 	Sessions *session.Store
 	Uploader storage.LocalUploader
+
+	DefaultCountry string
+	DefaultLang    string
+	GeoIP          *geoip.DB
 }
 
-func New(r *repo.Repository, s *service.Service, b *i18n.Bundle, sessions *session.Store, up storage.LocalUploader) *Handler {
-	return &Handler{Repo: r, Service: s, I18n: b, Sessions: sessions, Uploader: up}
+func New(r *repo.Repository, s *service.Service, b *i18n.Bundle, sessions *session.Store, up storage.LocalUploader, defaultCountry, defaultLang string, geo *geoip.DB) *Handler {
+	return &Handler{Repo: r, Service: s, I18n: b, Sessions: sessions, Uploader: up, DefaultCountry: defaultCountry, DefaultLang: defaultLang, GeoIP: geo}
 }
 
 func (h *Handler) lang(c *fiber.Ctx) string {
-	lang := c.Query("lang", c.Cookies("lang", "en"))
-	lang = service.AllowedLang(lang)
-	c.Cookie(&fiber.Cookie{Name: "lang", Value: lang, Path: "/", SameSite: "Lax"})
+	if lang, ok := c.Locals("lang").(string); ok {
+		return lang
+	}
+	lang := h.negotiateLang(c)
+	c.Locals("lang", lang)
 	return lang
 }
 
+// langs is the content fallback chain of the current language in the current
+// country: deal text and master data names come from its first language that
+// has them.
+func (h *Handler) langs(c *fiber.Ctx) []string {
+	var fb i18n.Fallbacks
+	if co := h.currentCountry(c); co != nil {
+		fb = co.FallbackChains
+	}
+	return h.I18n.Chain(h.lang(c), fb)
+}
+
+// negotiateLang picks among the current country's languages, trying in
+// order: ?lang= (remembered in the lang cookie), the lang cookie, the user's
+// preference, Accept-Language, the country's default language and
+// DEFAULT_LANG. It never writes to the database, as it runs on GETs; the
+// cookie reaches the user's profile at sign-in.
+func (h *Handler) negotiateLang(c *fiber.Ctx) string {
+	u, _ := c.Locals("user").(*models.User)
+	co := h.currentCountry(c)
+	langs := h.countryLanguages(co)
+	has := func(l string) bool {
+		for _, x := range langs {
+			if x == l {
+				return true
+			}
+		}
+		return false
+	}
+	if q := strings.ToLower(c.Query("lang")); has(q) {
+		c.Cookie(&fiber.Cookie{Name: "lang", Value: q, Path: "/", SameSite: "Lax"})
+		return q
+	}
+	if v := c.Cookies("lang"); has(v) {
+		return v
+	}
+	if u != nil && has(u.PreferredLang) {
+		return u.PreferredLang
+	}
+	c.Vary(fiber.HeaderAcceptLanguage)
+	if v := i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage), langs); v != "" {
+		return v
+	}
+	if co != nil && has(co.DefaultLanguage) {
+		return co.DefaultLanguage
+	}
+	if has(h.DefaultLang) || len(langs) == 0 {
+		return h.DefaultLang
+	}
+	return langs[0]
+}
+
+// t translates into the request's language.
+func (h *Handler) t(c *fiber.Ctx) i18n.Func {
+	return h.I18n.For(h.lang(c))
+}
+
 func (h *Handler) render(c *fiber.Ctx, title, country string, body template.HTML) error {
-	lang := h.lang(c)
 	var u *models.User
 	if v := c.Locals("user"); v != nil {
 		u = v.(*models.User)
 	}
-	html, err := views.Render(views.Layout(title, views.NavData{CountryCode: country, Lang: lang, User: u, T: func(k string) string { return h.I18n.T(lang, k) }}, body))
+	html, err := views.Render(views.Layout(title, views.NavData{CountryCode: country, Lang: h.lang(c), User: u, T: h.t(c)}, body))
 	if err != nil {
 		return err
 	}
@@ -51,121 +117,258 @@ func (h *Handler) render(c *fiber.Ctx, title, country string, body template.HTML
 }
 
 func (h *Handler) Home(c *fiber.Ctx) error {
-	cc := strings.ToUpper(c.Params("countryCode"))
-	featured, _ := h.Repo.FeaturedDeals(c.Context(), cc, 6)
-	ending, _ := h.Repo.EndingSoonDeals(c.Context(), cc, 6)
-	cats, _ := h.Repo.Categories(c.Context())
-	lang := h.lang(c)
-	return h.render(c, "Home", cc, views.HomeContent(featured, ending, cats, cc, func(k string) string { return h.I18n.T(lang, k) }))
+	cc := h.country(c)
+	langs := h.langs(c)
+	featured, _ := h.Repo.FeaturedDeals(c.Context(), cc, langs, 6)
+	ending, _ := h.Repo.EndingSoonDeals(c.Context(), cc, langs, 6)
+	cats, _ := h.Repo.Categories(c.Context(), langs)
+	t := h.t(c)
+	return h.render(c, t("home"), cc, views.HomeContent(featured, ending, cats, cc, t))
 }
 
 func (h *Handler) Deals(c *fiber.Ctx) error {
-	cc := strings.ToUpper(c.Params("countryCode"))
+	cc := h.country(c)
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	dt, _ := strconv.ParseInt(c.Query("deal_type", "0"), 10, 64)
 	mID, _ := strconv.ParseInt(c.Query("merchant", "0"), 10, 64)
-	deals, _ := h.Repo.ListDeals(c.Context(), models.DealFilter{CountryCode: cc, CitySlug: c.Query("city"), CategorySlug: c.Query("category"), DealTypeID: dt, MerchantID: mID, Search: c.Query("q"), EndingSoon: c.Query("ending_soon") == "1", Page: page, PageSize: 10})
-	partial := views.DealCards(deals, cc)
+	f := models.DealFilter{CountryCode: cc, CitySlug: c.Query("city"), CategorySlug: c.Query("category"), DealTypeID: dt, MerchantID: mID, Search: c.Query("q"), EndingSoon: c.Query("ending_soon") == "1", Langs: h.langs(c), Page: page, PageSize: 10}
+	res, err := h.Repo.ListDeals(c.Context(), f)
+	if err != nil {
+		return err
+	}
+	t := h.t(c)
+	browse := views.DealBrowse(res, f, cc, t)
 	if c.Get("HX-Request") == "true" {
-		return c.SendString(string(partial))
+		return c.Type("html").SendString(string(browse))
 	}
-	body := template.HTML(fmt.Sprintf("<h1>Deals</h1><form hx-get='/%s/deals' hx-target='#results'><input name='q' placeholder='search'><button>Search</button></form><div id='results'>%s</div>", cc, partial))
-	return h.render(c, "Deals", cc, body)
+	body := template.HTML(fmt.Sprintf("<h1>%s</h1>%s", t("deals"), browse))
+	return h.render(c, t("deals"), cc, body)
 }
 
 func (h *Handler) DealDetail(c *fiber.Ctx) error {
-	cc := strings.ToUpper(c.Params("countryCode"))
-	d, err := h.Repo.DealBySlug(c.Context(), cc, c.Params("dealSlug"))
+	cc := h.country(c)
+	d, err := h.Repo.DealBySlug(c.Context(), cc, c.Params("dealSlug"), h.langs(c))
 	if err != nil {
-		return c.SendStatus(404)
+		return fiber.ErrNotFound
 	}
-	tr, _ := h.Repo.DealTranslation(c.Context(), d.ID, h.lang(c))
-	return h.render(c, d.Title, cc, views.DealDetail(d, tr))
+	return h.render(c, d.Title, cc, views.DealDetail(d, h.t(c)))
 }
 
 func (h *Handler) RegisterForm(c *fiber.Ctx) error {
-	body := template.HTML("<h1>Register</h1><form method='post'><input name='name' placeholder='name'><input name='email' type='email'><input name='password' type='password'><input type='hidden' name='csrf' value='" + c.Locals("csrf").(string) + "'><button>Create account</button></form>")
-	return h.render(c, "Register", "LK", body)
+	return h.registerForm(c, nil)
+}
+
+func (h *Handler) registerForm(c *fiber.Ctx, verr *service.ValidationError) error {
+	t := h.t(c)
+	body := template.HTML(fmt.Sprintf("<h1>%s</h1><form method='post'><input name='name' placeholder='%s' value='%s'>%s<input name='email' type='email' value='%s'>%s<input name='password' type='password' minlength='%d'>%s<input type='hidden' name='csrf' value='%s'><button>%s</button></form>",
+		t("register"), t("name_placeholder"), template.HTMLEscapeString(formValue(c, "name", "")), fieldErrors(t, verr, "name"),
+		template.HTMLEscapeString(formValue(c, "email", "")), fieldErrors(t, verr, "email"), service.MinPasswordLen, fieldErrors(t, verr, "password"),
+		c.Locals("csrf"), t("create_account")))
+	return h.render(c, t("register"), h.lastCountry(c), body)
 }
 
 func (h *Handler) Register(c *fiber.Ctx) error {
-	hash, err := auth.HashPassword(c.FormValue("password"))
+	u, err := h.Service.Register(c.Context(), service.RegisterInput{Name: c.FormValue("name"), Email: c.FormValue("email"), Password: c.FormValue("password")})
+	if verr, ok := invalidForm(c, err); ok {
+		return h.registerForm(c, verr)
+	}
 	if err != nil {
 		return err
 	}
-	u := &models.User{Name: c.FormValue("name"), Email: strings.ToLower(c.FormValue("email")), PasswordHash: hash, Role: models.RoleSubmitter}
-	if err := h.Repo.CreateUser(c.Context(), u); err != nil {
-		return c.Status(400).SendString(err.Error())
+	if err := h.Service.SendVerification(c.Context(), u); err != nil {
+		log.Printf("send verification to user %d: %v", u.ID, err)
 	}
 	return c.Redirect("/account/login")
 }
 
 func (h *Handler) LoginForm(c *fiber.Ctx) error {
-	body := template.HTML("<h1>Login</h1><form method='post'><input name='email' type='email'><input name='password' type='password'><input type='hidden' name='csrf' value='" + c.Locals("csrf").(string) + "'><button>Login</button></form>")
-	return h.render(c, "Login", "LK", body)
+	t := h.t(c)
+	body := template.HTML(fmt.Sprintf("<h1>%s</h1><form method='post'><input name='email' type='email'><input name='password' type='password'><input type='hidden' name='csrf' value='%s'><button>%s</button></form><p><a href='/account/reset'>%s</a></p>", t("login"), c.Locals("csrf"), t("login"), t("forgot_password")))
+	return h.render(c, t("login"), h.lastCountry(c), body)
 }
 
 func (h *Handler) Login(c *fiber.Ctx) error {
-	u, err := h.Repo.UserByEmail(c.Context(), c.FormValue("email"))
-	if err != nil || auth.CheckPassword(u.PasswordHash, c.FormValue("password")) != nil {
-		return c.Status(400).SendString("invalid credentials")
+	u, err := h.Service.Authenticate(c.Context(), service.LoginRequest{
+		Email:     c.FormValue("email"),
+		Password:  c.FormValue("password"),
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	})
+	if err != nil {
+		return h.loginError(c, err)
+	}
+	if u.TOTPEnabledAt != nil {
+		return h.beginSecondFactor(c, u)
+	}
+	return h.startSession(c, u)
+}
+
+func (h *Handler) loginError(c *fiber.Ctx, err error) error {
+	t := h.t(c)
+	var te *service.ThrottleError
+	switch {
+	case errors.As(err, &te):
+		secs := int(te.RetryAfter.Seconds()) + 1
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(secs))
+		if te.Locked {
+			return userError(fiber.StatusTooManyRequests, t("error_locked", "minutes", (secs+59)/60))
+		}
+		return userError(fiber.StatusTooManyRequests, t("error_throttled", "seconds", secs))
+	case errors.Is(err, service.ErrInvalidCredentials):
+		return userError(400, t("error_invalid_credentials"))
+	}
+	return err
+}
+
+func (h *Handler) startSession(c *fiber.Ctx, u *models.User) error {
+	sess, err := h.Sessions.Get(c)
+	if err != nil {
+		return err
 	}
-	sess, _ := h.Sessions.Get(c)
+	if err := sess.Regenerate(); err != nil {
+		return err
+	}
+	sess.Delete(pendingUIDKey)
+	sess.Delete(pendingAtKey)
 	sess.Set("uid", fmt.Sprintf("%d", u.ID))
-	sess.Save()
-	return c.Redirect("/LK")
+	if u.TOTPEnabledAt != nil {
+		// Enrolled users only get here through SecondFactor.
+		sess.Set(middleware.SecondFactorKey, true)
+	}
+	sid := sess.ID()
+	if err := sess.Save(); err != nil {
+		return err
+	}
+	if l := c.Cookies("lang"); h.I18n.Has(l) && l != u.PreferredLang {
+		if err := h.Repo.SetUserLang(c.Context(), u.ID, l); err != nil {
+			log.Printf("save language for user %d: %v", u.ID, err)
+		}
+	}
+	if err := h.Repo.AssignSession(c.Context(), sid, u.ID, c.Get(fiber.HeaderUserAgent), c.IP()); err != nil {
+		return err
+	}
+	return c.Redirect("/" + h.lastCountry(c))
 }
 
 func (h *Handler) Logout(c *fiber.Ctx) error {
 	s, _ := h.Sessions.Get(c)
 	s.Destroy()
-	return c.Redirect("/LK")
+	return c.Redirect("/" + h.lastCountry(c))
 }
 
 func (h *Handler) SubmissionList(c *fiber.Ctx) error {
 	u := c.Locals("user").(*models.User)
 	items, _ := h.Repo.SubmissionDeals(c.Context(), u.ID)
-	body := template.HTML("<h1>My submissions</h1><a href='/account/submissions/new'>New</a>" + string(views.DealCards(items, "LK")))
-	return h.render(c, "My submissions", "LK", body)
+	t := h.t(c)
+	body := template.HTML(fmt.Sprintf("<h1>%s</h1><a href='/account/submissions/new'>%s</a>%s", t("my_submissions"), t("new_submission"), views.DealCards(items, h.lastCountry(c), t)))
+	return h.render(c, t("my_submissions"), h.lastCountry(c), body)
 }
 
 func (h *Handler) NewSubmissionForm(c *fiber.Ctx) error {
 	countries, _ := h.Repo.Countries(c.Context())
-	cities, _ := h.Repo.CitiesByCountry(c.Context(), countries[0].ID)
-	cats, _ := h.Repo.Categories(c.Context())
-	dts, _ := h.Repo.DealTypes(c.Context())
-	var cityOpts, catOpts, dtOpts strings.Builder
-	for _, x := range cities {
-		fmt.Fprintf(&cityOpts, "<option value='%d'>%s</option>", x.ID, x.Name)
-	}
+	return h.dealForm(c, countries, nil)
+}
+
+// dealForm renders the deal creation form for a deal in one of countries; the
+// deal's country follows from the chosen city. A rejected form keeps its
+// values and shows verr's messages.
+func (h *Handler) dealForm(c *fiber.Ctx, countries []models.Country, verr *service.ValidationError) error {
+	cats, _ := h.Repo.Categories(c.Context(), h.langs(c))
+	dts, _ := h.Repo.DealTypes(c.Context(), h.langs(c))
+	var catOpts, dtOpts strings.Builder
+	catID, dtID := formValue(c, "category_id", ""), formValue(c, "deal_type_id", "")
 	for _, x := range cats {
-		fmt.Fprintf(&catOpts, "<option value='%d'>%s</option>", x.ID, x.Name)
+		fmt.Fprintf(&catOpts, "<option value='%d'%s>%s</option>", x.ID, selectedIf(catID == strconv.FormatInt(x.ID, 10)), template.HTMLEscapeString(x.Name))
 	}
 	for _, x := range dts {
-		fmt.Fprintf(&dtOpts, "<option value='%d'>%s</option>", x.ID, x.Name)
+		fmt.Fprintf(&dtOpts, "<option value='%d'%s>%s</option>", x.ID, selectedIf(dtID == strconv.FormatInt(x.ID, 10)), template.HTMLEscapeString(x.Name))
+	}
+	t := h.t(c)
+	picker, selected := h.countryPicker(c, countries)
+	var posted map[string]models.DealTranslation
+	if c.Method() == fiber.MethodPost && selected != nil {
+		posted = map[string]models.DealTranslation{}
+		for _, tr := range h.formTranslations(c, selected, &models.Deal{}) {
+			posted[tr.Lang] = tr
+		}
+	}
+	translations := fmt.Sprintf("<div id='translations' hx-get='/account/translations' hx-trigger='change from:select[name=country_id]' hx-include='select[name=country_id]'>%s</div>", h.translationInputs(t, selected, posted))
+	body := template.HTML(fmt.Sprintf("<h1>%s</h1><form method='post' enctype='multipart/form-data'><input name='title' value='%s'>%s<textarea name='description'>%s</textarea>%s%s<select name='category_id'>%s</select>%s<select name='deal_type_id'>%s</select>%s<input name='start_at' type='date' value='%s'>%s<input name='end_at' type='date' value='%s'>%s<input type='file' name='image'>%s%s%s<input type='hidden' name='csrf' value='%s'><button>%s</button></form>",
+		t("submit_deal"), template.HTMLEscapeString(formValue(c, "title", "")), fieldErrors(t, verr, "title"), template.HTMLEscapeString(formValue(c, "description", "")),
+		picker, fieldErrors(t, verr, "city_id"), catOpts.String(), fieldErrors(t, verr, "category_id"), dtOpts.String(), fieldErrors(t, verr, "deal_type_id"),
+		template.HTMLEscapeString(formValue(c, "start_at", "")), fieldErrors(t, verr, "start_at"), template.HTMLEscapeString(formValue(c, "end_at", "")), fieldErrors(t, verr, "end_at"),
+		fieldErrors(t, verr, "image"), translations, featuredInput(c, t), c.Locals("csrf"), t("submit")))
+	return h.render(c, t("submit_deal"), h.lastCountry(c), body)
+}
+
+func featuredInput(c *fiber.Ctx, t i18n.Func) string {
+	if u, _ := c.Locals("user").(*models.User); u.Can(models.PermCreateFeatured) {
+		checked := ""
+		if formValue(c, "featured", "") == "on" {
+			checked = " checked"
+		}
+		return "<label><input type='checkbox' name='featured'" + checked + "> " + t("featured") + "</label>"
 	}
-	body := template.HTML(fmt.Sprintf("<h1>Submit Deal</h1><form method='post' enctype='multipart/form-data'><input name='title'><textarea name='description'></textarea><select name='city_id'>%s</select><select name='category_id'>%s</select><select name='deal_type_id'>%s</select><input name='start_at' type='date'><input name='end_at' type='date'><input type='file' name='image'><input name='title_si' placeholder='Sinhala title'><textarea name='description_si'></textarea><input name='title_ta' placeholder='Tamil title'><textarea name='description_ta'></textarea><input type='hidden' name='csrf' value='%s'><button>Submit</button></form>", cityOpts.String(), catOpts.String(), dtOpts.String(), c.Locals("csrf")))
-	return h.render(c, "New submission", "LK", body)
+	return ""
 }
 
 func (h *Handler) CreateSubmission(c *fiber.Ctx) error {
+	countries, err := h.Repo.Countries(c.Context())
+	if err != nil {
+		return err
+	}
+	return h.createDeal(c, countries)
+}
+
+// createDeal saves the posted deal form as a pending deal by the current user
+// in one of countries, or shows the form again when the input is invalid.
+func (h *Handler) createDeal(c *fiber.Ctx, countries []models.Country) error {
 	u := c.Locals("user").(*models.User)
-	country, _ := h.Repo.CountryByCode(c.Context(), "LK")
-	slug := strings.ToLower(strings.ReplaceAll(c.FormValue("title"), " ", "-"))
-	if exists, _ := h.Repo.SlugExists(c.Context(), country.ID, slug); exists {
-		slug += "-" + strconv.FormatInt(u.ID, 10)
-	}
-	cityID, _ := strconv.ParseInt(c.FormValue("city_id"), 10, 64)
-	catID, _ := strconv.ParseInt(c.FormValue("category_id"), 10, 64)
-	dtID, _ := strconv.ParseInt(c.FormValue("deal_type_id"), 10, 64)
-	start, _ := service.ParseDate(c.FormValue("start_at"))
-	end, _ := service.ParseDate(c.FormValue("end_at"))
+	d, err := h.Service.ValidateDeal(c.Context(), service.DealInput{
+		Title:       c.FormValue("title"),
+		Description: c.FormValue("description"),
+		CountryID:   c.FormValue("country_id"),
+		CityID:      c.FormValue("city_id"),
+		CategoryID:  c.FormValue("category_id"),
+		DealTypeID:  c.FormValue("deal_type_id"),
+		StartAt:     c.FormValue("start_at"),
+		EndAt:       c.FormValue("end_at"),
+	})
+	if verr, ok := invalidForm(c, err); ok {
+		return h.dealForm(c, countries, verr)
+	}
+	if err != nil {
+		return err
+	}
+	var country *models.Country
+	for i := range countries {
+		if countries[i].ID == d.CountryID {
+			country = &countries[i]
+		}
+	}
+	if country == nil {
+		return fiber.ErrForbidden
+	}
 	img, err := h.Uploader.Save(c, "image")
 	if err != nil {
-		return c.Status(400).SendString(err.Error())
+		verr := &service.ValidationError{}
+		verr.Add("image", "error_image")
+		c.Status(fiber.StatusUnprocessableEntity)
+		return h.dealForm(c, countries, verr)
+	}
+	if d.Slug, err = h.Service.UniqueSlug(c.Context(), d.CountryID, d.Title); err != nil {
+		return err
+	}
+	d.ImageURL = img
+	d.Status = models.DealPending
+	d.CreatedByUserID = u.ID
+	d.Featured = u.Can(models.PermCreateFeatured) && c.FormValue("featured") == "on"
+	var trs []models.DealTranslation
+	for _, t := range h.formTranslations(c, country, d) {
+		if t.Title != "" {
+			trs = append(trs, t)
+		}
 	}
-	d := &models.Deal{Title: c.FormValue("title"), Slug: slug, Description: c.FormValue("description"), CountryID: country.ID, CityID: cityID, CategoryID: catID, DealTypeID: dtID, StartAt: start, EndAt: end, ImageURL: img, Status: models.DealPending, CreatedByUserID: u.ID}
-	trs := []models.DealTranslation{{Lang: "en", Title: d.Title, Description: d.Description}, {Lang: "si", Title: c.FormValue("title_si"), Description: c.FormValue("description_si")}, {Lang: "ta", Title: c.FormValue("title_ta"), Description: c.FormValue("description_ta")}}
 	if err := h.Repo.CreateDeal(c.Context(), d, trs); err != nil {
 		return err
 	}
@@ -176,93 +379,296 @@ func (h *Handler) EditSubmissionForm(c *fiber.Ctx) error {
 	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
 	d, err := h.Repo.DealByID(c.Context(), id)
 	if err != nil {
-		return c.SendStatus(404)
+		return fiber.ErrNotFound
 	}
 	u := c.Locals("user").(*models.User)
-	if !service.DealEditable(d, u.ID, u.Role == models.RoleAdmin) {
-		return c.SendStatus(403)
+	if !service.DealEditable(d, u.ID, u.CanIn(models.PermModerate, d.CountryID)) {
+		return fiber.ErrForbidden
 	}
-	body := template.HTML(fmt.Sprintf("<h1>Edit</h1><form method='post' enctype='multipart/form-data'><input name='title' value='%s'><textarea name='description'>%s</textarea><input name='start_at' type='date' value='%s'><input name='end_at' type='date' value='%s'><input type='file' name='image'><input type='hidden' name='csrf' value='%s'><button>Save</button></form>", d.Title, d.Description, d.StartAt.Format("2006-01-02"), d.EndAt.Format("2006-01-02"), c.Locals("csrf")))
-	return h.render(c, "Edit submission", "LK", body)
+	country, err := h.Repo.CountryByID(c.Context(), d.CountryID)
+	if err != nil {
+		return err
+	}
+	return h.editDealForm(c, d, country, nil)
+}
+
+// editDealForm renders the edit form of d, filled with the submitted values
+// and verr's messages when an edit was rejected.
+func (h *Handler) editDealForm(c *fiber.Ctx, d *models.Deal, country *models.Country, verr *service.ValidationError) error {
+	existing := map[string]models.DealTranslation{}
+	if c.Method() == fiber.MethodPost {
+		for _, tr := range h.formTranslations(c, country, d) {
+			existing[tr.Lang] = tr
+		}
+	} else {
+		trs, err := h.Repo.DealTranslations(c.Context(), d.ID)
+		if err != nil {
+			return err
+		}
+		for _, tr := range trs {
+			existing[tr.Lang] = tr
+		}
+	}
+	t := h.t(c)
+	body := template.HTML(fmt.Sprintf("<h1>%s</h1><form method='post' enctype='multipart/form-data'><input name='title' value='%s'>%s<textarea name='description'>%s</textarea><input name='start_at' type='date' value='%s'>%s<input name='end_at' type='date' value='%s'>%s<input type='file' name='image'>%s%s<input type='hidden' name='csrf' value='%s'><button>%s</button></form>",
+		t("edit_submission"), template.HTMLEscapeString(formValue(c, "title", d.Title)), fieldErrors(t, verr, "title"), template.HTMLEscapeString(formValue(c, "description", d.Description)),
+		template.HTMLEscapeString(formValue(c, "start_at", d.StartAt.Format("2006-01-02"))), fieldErrors(t, verr, "start_at"),
+		template.HTMLEscapeString(formValue(c, "end_at", d.EndAt.Format("2006-01-02"))), fieldErrors(t, verr, "end_at"),
+		fieldErrors(t, verr, "image"), h.translationInputs(t, country, existing), c.Locals("csrf"), t("save")))
+	return h.render(c, t("edit_submission"), h.lastCountry(c), body)
 }
 
 func (h *Handler) UpdateSubmission(c *fiber.Ctx) error {
 	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
 	d, err := h.Repo.DealByID(c.Context(), id)
 	if err != nil {
-		return c.SendStatus(404)
+		return fiber.ErrNotFound
 	}
 	u := c.Locals("user").(*models.User)
-	if !service.DealEditable(d, u.ID, u.Role == models.RoleAdmin) {
-		return c.SendStatus(403)
-	}
-	if img, err := h.Uploader.Save(c, "image"); err == nil && img != "" {
-		d.ImageURL = img
+	if !service.DealEditable(d, u.ID, u.CanIn(models.PermModerate, d.CountryID)) {
+		return fiber.ErrForbidden
 	}
-	d.Title = c.FormValue("title")
-	d.Description = c.FormValue("description")
-	d.StartAt, _ = service.ParseDate(c.FormValue("start_at"))
-	d.EndAt, _ = service.ParseDate(c.FormValue("end_at"))
-	if err := h.Repo.UpdateSubmission(c.Context(), d); err != nil {
+	country, err := h.Repo.CountryByID(c.Context(), d.CountryID)
+	if err != nil {
 		return err
 	}
+	v := &service.ValidationError{}
+	service.ApplyDealText(v, service.DealInput{Title: c.FormValue("title"), Description: c.FormValue("description"), StartAt: c.FormValue("start_at"), EndAt: c.FormValue("end_at")}, d)
+	if v.Err() == nil {
+		if img, err := h.Uploader.Save(c, "image"); err != nil {
+			v.Add("image", "error_image")
+		} else if img != "" {
+			d.ImageURL = img
+		}
+	}
+	if verr, ok := invalidForm(c, v.Err()); ok {
+		return h.editDealForm(c, d, country, verr)
+	}
+	if err := h.Service.SaveSubmission(c.Context(), d, h.formTranslations(c, country, d), u); err != nil {
+		return h.workflowError(c, err)
+	}
 	return c.Redirect("/account/submissions")
 }
 
+//i18n:keys moderation translations create_deal users config master_data
+var adminLinks = []struct {
+	href, key string
+	perm      models.Permission
+}{
+	{"/admin/moderation", "moderation", models.PermModerate},
+	{"/admin/translations", "translations", models.PermTranslate},
+	{"/admin/deals/new", "create_deal", models.PermModerate},
+	{"/admin/users", "users", models.PermManageUsers},
+	{"/admin/config", "config", models.PermEditConfig},
+	{"/admin/master", "master_data", models.PermManageMasterData},
+}
+
 func (h *Handler) AdminDashboard(c *fiber.Ctx) error {
-	p, pub, _ := h.Repo.DashboardCounts(c.Context())
-	body := template.HTML(fmt.Sprintf("<h1>Admin</h1><p>Pending: %d, Published: %d</p><ul><li><a href='/admin/moderation'>Moderation</a></li><li><a href='/admin/deals/new'>Create Deal</a></li><li><a href='/admin/users'>Users</a></li><li><a href='/admin/config'>Config</a></li><li><a href='/admin/master'>Master Data</a></li></ul>", p, pub))
-	return h.render(c, "Admin", "LK", body)
+	u := c.Locals("user").(*models.User)
+	ids, filter, err := h.adminCountryFilter(c, "/admin")
+	if err != nil {
+		return err
+	}
+	p, pub, _ := h.Repo.DashboardCounts(c.Context(), ids)
+	runs, _ := h.Repo.LatestJobRuns(c.Context())
+	t := h.t(c)
+	var links strings.Builder
+	for _, l := range adminLinks {
+		if u.Can(l.perm) {
+			fmt.Fprintf(&links, "<li><a href='%s'>%s</a></li>", l.href, t(l.key))
+		}
+	}
+	body := template.HTML(fmt.Sprintf("<h1>%s</h1>%s<p>%s</p><ul>%s</ul>%s", t("admin"), filter, t("dashboard_counts", "pending", p, "published", pub), links.String(), views.JobRuns(runs, t)))
+	return h.render(c, t("admin"), h.lastCountry(c), body)
 }
 
 func (h *Handler) AdminModeration(c *fiber.Ctx) error {
-	items, _ := h.Repo.PendingDeals(c.Context())
+	ids, filter, err := h.adminCountryFilter(c, "/admin/moderation")
+	if err != nil {
+		return err
+	}
+	items, _ := h.Repo.PendingDeals(c.Context(), ids)
+	t := h.t(c)
 	var b strings.Builder
-	b.WriteString("<h1>Moderation Queue</h1>")
+	fmt.Fprintf(&b, "<h1>%s</h1>%s", t("moderation_queue"), filter)
 	for _, d := range items {
-		fmt.Fprintf(&b, "<div><b>%s</b><form method='post' action='/admin/moderation/%d'><input type='hidden' name='csrf' value='%s'><button name='action' value='approve'>Approve</button><input name='reason' placeholder='reason'><button name='action' value='reject'>Reject</button></form></div>", d.Title, d.ID, c.Locals("csrf"))
+		fmt.Fprintf(&b, "<div>[%s] <b>%s</b> <a href='/admin/deals/%d/revisions'>%s</a><form method='post' action='/admin/moderation/%d'><input type='hidden' name='csrf' value='%s'><button name='action' value='approve'>%s</button><input name='reason' placeholder='%s'><button name='action' value='reject'>%s</button></form></div>", template.HTMLEscapeString(d.CountryCode), template.HTMLEscapeString(d.Title), d.ID, t("revisions"), d.ID, c.Locals("csrf"), t("approve"), t("reason_placeholder"), t("reject"))
 	}
-	return h.render(c, "Moderation", "LK", template.HTML(b.String()))
+	machine, _ := h.Repo.CountTranslationQueue(c.Context(), ids, h.I18n.Languages(), "", models.TranslationMachine)
+	fmt.Fprintf(&b, "<p><a href='/admin/translations?status=machine'>%s</a></p>", t("machine_translations_pending", "count", machine))
+	return h.render(c, t("moderation"), h.lastCountry(c), template.HTML(b.String()))
 }
 
 func (h *Handler) AdminModerate(c *fiber.Ctx) error {
 	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
-	a := c.FormValue("action")
-	if a == "approve" {
-		_ = h.Repo.ModerateDeal(c.Context(), id, models.DealApproved, nil)
-		_ = h.Repo.UpdateDealStatusDirect(c.Context(), id, models.DealPublished)
+	d, err := h.Repo.DealByID(c.Context(), id)
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	u := c.Locals("user").(*models.User)
+	if !u.InCountry(d.CountryID) {
+		return fiber.ErrForbidden
 	}
-	if a == "reject" {
+	switch c.FormValue("action") {
+	case "approve":
+		steps := []models.DealStatus{models.DealApproved}
+		if !d.StartAt.After(time.Now()) {
+			steps = append(steps, models.DealPublished)
+		}
+		err = h.Service.TransitionDeal(c.Context(), id, u, nil, steps...)
+	case "reject":
 		r := c.FormValue("reason")
-		_ = h.Repo.ModerateDeal(c.Context(), id, models.DealRejected, &r)
+		err = h.Service.TransitionDeal(c.Context(), id, u, &r, models.DealRejected)
+	default:
+		return fiber.ErrBadRequest
+	}
+	if err != nil {
+		return h.workflowError(c, err)
 	}
 	return c.Redirect("/admin/moderation")
 }
 
+func (h *Handler) AdminDealRevisions(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	d, err := h.Repo.DealByID(c.Context(), id)
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	if !c.Locals("user").(*models.User).InCountry(d.CountryID) {
+		return fiber.ErrForbidden
+	}
+	revs, err := h.Repo.DealRevisions(c.Context(), id)
+	if err != nil {
+		return err
+	}
+	history, err := h.Repo.DealStatusHistory(c.Context(), id)
+	if err != nil {
+		return err
+	}
+	diffs := make([][]models.FieldChange, len(revs))
+	for i, rv := range revs {
+		var prev models.DealSnapshot
+		if i+1 < len(revs) {
+			prev = revs[i+1].Snapshot
+		}
+		diffs[i] = service.DiffSnapshots(prev, rv.Snapshot)
+	}
+	t := h.t(c)
+	return h.render(c, t("revisions"), d.CountryCode, views.DealRevisions(d, revs, diffs, history, c.Locals("csrf").(string), t))
+}
+
+func (h *Handler) AdminRestoreRevision(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	rev, _ := strconv.ParseInt(c.Params("rev"), 10, 64)
+	u := c.Locals("user").(*models.User)
+	d, err := h.Repo.DealByID(c.Context(), id)
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	if !u.InCountry(d.CountryID) {
+		return fiber.ErrForbidden
+	}
+	if err := h.Service.RestoreRevision(c.Context(), id, rev, u); err != nil {
+		return h.workflowError(c, err)
+	}
+	return c.Redirect(fmt.Sprintf("/admin/deals/%d/revisions", id))
+}
+
+// workflowError maps deal workflow failures to responses: 404 for a deal
+// that is gone and 409 for a transition its status or actor does not allow.
+func (h *Handler) workflowError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fiber.ErrNotFound
+	}
+	var te *service.TransitionError
+	if !errors.As(err, &te) {
+		return err
+	}
+	t := h.t(c)
+	from := t("deal_status_name", "status", string(te.From))
+	to := t("deal_status_name", "status", string(te.To))
+	switch {
+	case errors.Is(err, service.ErrTransitionNotAllowed):
+		return userError(fiber.StatusConflict, t("error_transition_not_allowed", "from", from, "to", to))
+	case errors.Is(err, service.ErrInvalidTransition):
+		return userError(fiber.StatusConflict, t("error_invalid_transition", "from", from, "to", to))
+	}
+	return err
+}
+
 func (h *Handler) AdminUsers(c *fiber.Ctx) error {
-	users, _ := h.Repo.Users(c.Context())
+	users, err := h.Repo.Users(c.Context())
+	if err != nil {
+		return err
+	}
+	me := c.Locals("user").(*models.User)
+	t := h.t(c)
 	var b strings.Builder
-	b.WriteString("<h1>Users</h1>")
+	fmt.Fprintf(&b, "<h1>%s</h1><p><a href='/admin/users/logins'>%s</a></p><table><tr><th>%s</th><th>%s</th></tr>", t("users"), t("login_audit"), t("role"), t("permissions"))
+	for _, r := range models.UserRoles {
+		perms := make([]string, 0, len(r.Permissions()))
+		for _, p := range r.Permissions() {
+			perms = append(perms, t("permission_name", "permission", string(p)))
+		}
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td></tr>", t("role_name", "role", string(r)), strings.Join(perms, ", "))
+	}
+	b.WriteString("</table>")
 	for _, u := range users {
-		fmt.Fprintf(&b, "<div>%s (%s) <form method='post' action='/admin/users/%d/role'><select name='role'><option value='submitter'>submitter</option><option value='admin'>admin</option></select><input type='hidden' name='csrf' value='%s'><button>Update</button></form></div>", u.Email, u.Role, u.ID, c.Locals("csrf"))
+		if !me.Manages(&u) {
+			continue
+		}
+		fmt.Fprintf(&b, "<div>%s (%s) <form method='post' action='/admin/users/%d/role'><select name='role'>%s</select><input type='hidden' name='csrf' value='%s'><button>%s</button></form> <form method='post' action='/admin/users/%d/logout'><input type='hidden' name='csrf' value='%s'><button>%s</button></form> <a href='/admin/users/%d/tokens'>%s</a> <a href='/admin/users/%d/countries'>%s</a>%s</div>", template.HTMLEscapeString(u.Email), t("role_name", "role", string(u.Role)), u.ID, roleOptions(me, u.Role, t), c.Locals("csrf"), t("update"), u.ID, c.Locals("csrf"), t("force_logout"), u.ID, t("api_tokens"), u.ID, t("countries"), lockStatus(u, c.Locals("csrf"), t))
+	}
+	return h.render(c, t("users"), h.lastCountry(c), template.HTML(b.String()))
+}
+
+// roleOptions lists the roles me may grant, plus the current one.
+func roleOptions(me *models.User, current models.UserRole, t i18n.Func) string {
+	var b strings.Builder
+	for _, r := range models.UserRoles {
+		if r != current && !me.CanGrant(r) {
+			continue
+		}
+		sel := ""
+		if r == current {
+			sel = " selected"
+		}
+		fmt.Fprintf(&b, "<option value='%s'%s>%s</option>", r, sel, t("role_name", "role", string(r)))
 	}
-	return h.render(c, "Users", "LK", template.HTML(b.String()))
+	return b.String()
 }
 
 func (h *Handler) AdminUserRole(c *fiber.Ctx) error {
-	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
-	_ = h.Repo.UpdateUserRole(c.Context(), id, models.UserRole(c.FormValue("role")))
+	target, err := h.managedUser(c)
+	if err != nil {
+		return err
+	}
+	role := models.UserRole(c.FormValue("role"))
+	if !role.Valid() {
+		return userError(400, h.t(c)("error_unknown_role"))
+	}
+	u := c.Locals("user").(*models.User)
+	if u.ID == target.ID && !role.Can(models.PermManageUsers) {
+		return userError(400, h.t(c)("error_own_user_management"))
+	}
+	if role != target.Role && !u.CanGrant(role) {
+		return userError(fiber.StatusForbidden, h.t(c)("error_grant_role"))
+	}
+	if err := h.Repo.UpdateUserRole(c.Context(), target.ID, role); err != nil {
+		return err
+	}
 	return c.Redirect("/admin/users")
 }
 
 func (h *Handler) AdminConfig(c *fiber.Ctx) error {
 	cfg, _ := h.Repo.AdminConfigs(c.Context())
+	t := h.t(c)
 	var b strings.Builder
-	b.WriteString("<h1>Config</h1><form method='post'><input name='key'><textarea name='value'>{}</textarea><input type='hidden' name='csrf' value='" + c.Locals("csrf").(string) + "'><button>Save</button></form>")
+	fmt.Fprintf(&b, "<h1>%s</h1><form method='post'><input name='key'><textarea name='value'>{}</textarea><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", t("config"), c.Locals("csrf"), t("save"))
 	for _, x := range cfg {
 		fmt.Fprintf(&b, "<pre>%s = %s</pre>", x.Key, string(x.Value))
 	}
-	return h.render(c, "Config", "LK", template.HTML(b.String()))
+	return h.render(c, t("config"), h.lastCountry(c), template.HTML(b.String()))
 }
 
 func (h *Handler) SaveConfig(c *fiber.Ctx) error {
@@ -271,48 +677,130 @@ func (h *Handler) SaveConfig(c *fiber.Ctx) error {
 }
 
 func (h *Handler) AdminMaster(c *fiber.Ctx) error {
+	return h.adminMaster(c, "", nil)
+}
+
+// adminMaster renders the master data page; when the create form named form
+// was rejected, that form keeps its values and shows verr's messages.
+func (h *Handler) adminMaster(c *fiber.Ctx, form string, verr *service.ValidationError) error {
 	countries, _ := h.Repo.Countries(c.Context())
-	cats, _ := h.Repo.Categories(c.Context())
-	mers, _ := h.Repo.Merchants(c.Context())
-	dts, _ := h.Repo.DealTypes(c.Context())
-	body := "<h1>Master data</h1>"
-	body += "<h2>Create Country</h2><form method='post' action='/admin/master/country'><input name='code'><input name='name'><input name='default_language' value='en'><input type='hidden' name='csrf' value='" + c.Locals("csrf").(string) + "'><button>Save</button></form>"
-	body += "<h2>Create City</h2><form method='post' action='/admin/master/city'><input name='country_id'><input name='name'><input name='slug'><input type='hidden' name='csrf' value='" + c.Locals("csrf").(string) + "'><button>Save</button></form>"
-	body += "<h2>Create Category</h2><form method='post' action='/admin/master/category'><input name='name'><input name='slug'><input type='hidden' name='csrf' value='" + c.Locals("csrf").(string) + "'><button>Save</button></form>"
-	body += "<h2>Create Merchant</h2><form method='post' action='/admin/master/merchant'><input name='name'><input name='slug'><input name='contact'><input type='hidden' name='csrf' value='" + c.Locals("csrf").(string) + "'><button>Save</button></form>"
-	body += "<h2>Create Deal Type</h2><form method='post' action='/admin/master/dealtype'><input name='code'><input name='name'><input type='hidden' name='csrf' value='" + c.Locals("csrf").(string) + "'><button>Save</button></form>"
-	body += fmt.Sprintf("<pre>countries:%d categories:%d merchants:%d dealtypes:%d</pre>", len(countries), len(cats), len(mers), len(dts))
-	return h.render(c, "Master", "LK", template.HTML(body))
+	// No languages lists the base names the translations fall back to.
+	cats, _ := h.Repo.Categories(c.Context(), nil)
+	mers, _ := h.Repo.Merchants(c.Context(), nil)
+	dts, _ := h.Repo.DealTypes(c.Context(), nil)
+	u := c.Locals("user").(*models.User)
+	t := h.t(c)
+	csrf := c.Locals("csrf").(string)
+	save := "<input type='hidden' name='csrf' value='" + csrf + "'><button>" + t("save") + "</button></form>"
+	input := func(f, name string) string {
+		value := ""
+		if f == form {
+			value = c.FormValue(name)
+		}
+		return fmt.Sprintf("<input name='%s' value='%s'>", name, template.HTMLEscapeString(value))
+	}
+	errs := func(f string, fields ...string) string {
+		if f != form {
+			return ""
+		}
+		out := ""
+		for _, field := range fields {
+			out += fieldErrors(t, verr, field)
+		}
+		return out
+	}
+	var countryOpts, countryLangs, langOpts strings.Builder
+	var cityRows, catRows, merRows, dtRows []masterRow
+	for _, co := range countries {
+		if u.InCountry(co.ID) {
+			fmt.Fprintf(&countryOpts, "<option value='%d'%s>%s</option>", co.ID, selectedIf(form == "city" && c.FormValue("country_id") == strconv.FormatInt(co.ID, 10)), template.HTMLEscapeString(co.Name))
+			fmt.Fprintf(&countryLangs, "<form method='post' action='/admin/master/country/%d/languages'>%s: %s<input name='fallbacks' placeholder='%s' value='%s'>%s", co.ID, template.HTMLEscapeString(co.Name), h.languageCheckboxes(co.Languages), t("fallbacks_placeholder"), template.HTMLEscapeString(co.Fallbacks), save)
+			cities, _ := h.Repo.CitiesByCountry(c.Context(), co.ID, nil)
+			for _, x := range cities {
+				cityRows = append(cityRows, masterRow{x.ID, x.Name + " (" + co.Code + ")"})
+			}
+		}
+	}
+	for _, x := range cats {
+		catRows = append(catRows, masterRow{x.ID, x.Name})
+	}
+	for _, x := range mers {
+		merRows = append(merRows, masterRow{x.ID, x.Name})
+	}
+	for _, x := range dts {
+		dtRows = append(dtRows, masterRow{x.ID, x.Name})
+	}
+	for _, l := range h.I18n.Languages() {
+		fmt.Fprintf(&langOpts, "<option value='%s'%s>%s</option>", l, selectedIf(form == "country" && c.FormValue("default_language") == l), h.I18n.Name(l))
+	}
+	var countryChecked []string
+	if form == "country" {
+		countryChecked = h.formLanguages(c)
+	}
+	body := "<h1>" + t("master_data") + "</h1>"
+	if u.AllCountries {
+		body += "<h2>" + t("create_country") + "</h2><form method='post' action='/admin/master/country'>" + input("country", "code") + input("country", "name") + "<select name='default_language'>" + langOpts.String() + "</select>" + h.languageCheckboxes(countryChecked) + errs("country", "code", "name", "default_language", "languages") + save
+	}
+	body += "<h2>" + t("country_languages") + "</h2><p>" + t("country_languages_help") + "</p>" + countryLangs.String()
+	body += "<h2>" + t("create_city") + "</h2><form method='post' action='/admin/master/city'><select name='country_id'>" + countryOpts.String() + "</select>" + input("city", "name") + input("city", "slug") + errs("city", "country_id", "name", "slug") + save
+	body += "<h2>" + t("create_category") + "</h2><form method='post' action='/admin/master/category'>" + input("category", "name") + input("category", "slug") + errs("category", "name", "slug") + save
+	body += "<h2>" + t("create_merchant") + "</h2><form method='post' action='/admin/master/merchant'>" + input("merchant", "name") + input("merchant", "slug") + input("merchant", "contact") + errs("merchant", "name", "slug") + save
+	body += "<h2>" + t("create_deal_type") + "</h2><form method='post' action='/admin/master/dealtype'>" + input("dealtype", "code") + input("dealtype", "name") + errs("dealtype", "code", "name") + save
+	body += "<h2>" + t("master_translations") + "</h2><p>" + t("master_translations_help") + "</p>"
+	body += h.masterTranslationForms(c, models.EntityCity, cityRows) + h.masterTranslationForms(c, models.EntityCategory, catRows) +
+		h.masterTranslationForms(c, models.EntityDealType, dtRows) + h.masterTranslationForms(c, models.EntityMerchant, merRows)
+	body += "<p>" + t("master_counts", "countries", len(countries), "categories", len(cats), "merchants", len(mers), "deal_types", len(dts)) + "</p>"
+	return h.render(c, t("master_data"), h.lastCountry(c), template.HTML(body))
 }
 
 func (h *Handler) CreateCountry(c *fiber.Ctx) error {
-	_ = h.Repo.CreateCountry(c.Context(), &models.Country{Code: strings.ToUpper(c.FormValue("code")), Name: c.FormValue("name"), DefaultLanguage: c.FormValue("default_language")})
-	return c.Redirect("/admin/master")
+	if !c.Locals("user").(*models.User).AllCountries {
+		return fiber.ErrForbidden
+	}
+	co := &models.Country{Code: c.FormValue("code"), Name: c.FormValue("name"), DefaultLanguage: c.FormValue("default_language"), Languages: h.formLanguages(c)}
+	return h.masterCreated(c, "country", h.Service.CreateCountry(c.Context(), co, h.I18n.Languages()))
 }
 func (h *Handler) CreateCity(c *fiber.Ctx) error {
-	cid, _ := strconv.ParseInt(c.FormValue("country_id"), 10, 64)
-	_ = h.Repo.CreateCity(c.Context(), &models.City{CountryID: cid, Name: c.FormValue("name"), Slug: c.FormValue("slug")})
-	return c.Redirect("/admin/master")
+	// An unparsable country_id is left as 0 for CreateCity to reject.
+	cid, err := strconv.ParseInt(c.FormValue("country_id"), 10, 64)
+	if err == nil && !c.Locals("user").(*models.User).InCountry(cid) {
+		return fiber.ErrForbidden
+	}
+	return h.masterCreated(c, "city", h.Service.CreateCity(c.Context(), &models.City{CountryID: cid, Name: c.FormValue("name"), Slug: c.FormValue("slug")}))
 }
 func (h *Handler) CreateCategory(c *fiber.Ctx) error {
-	_ = h.Repo.CreateCategory(c.Context(), &models.Category{Name: c.FormValue("name"), Slug: c.FormValue("slug")})
-	return c.Redirect("/admin/master")
+	return h.masterCreated(c, "category", h.Service.CreateCategory(c.Context(), &models.Category{Name: c.FormValue("name"), Slug: c.FormValue("slug")}))
 }
 func (h *Handler) CreateMerchant(c *fiber.Ctx) error {
-	_ = h.Repo.CreateMerchant(c.Context(), &models.Merchant{Name: c.FormValue("name"), Slug: c.FormValue("slug"), Contact: c.FormValue("contact")})
-	return c.Redirect("/admin/master")
+	return h.masterCreated(c, "merchant", h.Service.CreateMerchant(c.Context(), &models.Merchant{Name: c.FormValue("name"), Slug: c.FormValue("slug"), Contact: c.FormValue("contact")}))
 }
 func (h *Handler) CreateDealType(c *fiber.Ctx) error {
-	_ = h.Repo.CreateDealType(c.Context(), &models.DealType{Code: c.FormValue("code"), Name: c.FormValue("name")})
+	return h.masterCreated(c, "dealtype", h.Service.CreateDealType(c.Context(), &models.DealType{Code: c.FormValue("code"), Name: c.FormValue("name")}))
+}
+
+// masterCreated goes back to the master data page after a create form was
+// posted, showing form again when err rejects its input.
+func (h *Handler) masterCreated(c *fiber.Ctx, form string, err error) error {
+	if verr, ok := invalidForm(c, err); ok {
+		return h.adminMaster(c, form, verr)
+	}
+	if err != nil {
+		return err
+	}
 	return c.Redirect("/admin/master")
 }
 
 func (h *Handler) AdminNewDealForm(c *fiber.Ctx) error {
-	return h.NewSubmissionForm(c)
+	countries, err := h.scopedCountries(c)
+	if err != nil {
+		return err
+	}
+	return h.dealForm(c, countries, nil)
 }
 func (h *Handler) AdminCreateDeal(c *fiber.Ctx) error {
-	if err := h.CreateSubmission(c); err != nil {
+	countries, err := h.scopedCountries(c)
+	if err != nil {
 		return err
 	}
-	return nil
+	return h.createDeal(c, countries)
 }
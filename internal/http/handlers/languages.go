@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"go-next-cms/internal/i18n"
+	"go-next-cms/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// countryLanguages lists the bundle languages enabled for co, its default
+// language first. A country without a list, or a nil country, gets every
+// bundle language.
+func (h *Handler) countryLanguages(co *models.Country) []string {
+	all := h.I18n.Languages()
+	if co == nil {
+		return all
+	}
+	enabled := co.Languages
+	if len(enabled) == 0 {
+		enabled = all
+	}
+	out := []string{}
+	if h.I18n.Has(co.DefaultLanguage) {
+		out = append(out, co.DefaultLanguage)
+	}
+	for _, l := range enabled {
+		if l != co.DefaultLanguage && h.I18n.Has(l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// translationLanguages are the languages that get their own translation
+// inputs on deal forms: the main title and description are in the country's
+// default language.
+func (h *Handler) translationLanguages(co *models.Country) []string {
+	langs := h.countryLanguages(co)
+	if co != nil && len(langs) > 0 && langs[0] == co.DefaultLanguage {
+		return langs[1:]
+	}
+	return langs
+}
+
+func (h *Handler) translationInputs(t i18n.Func, co *models.Country, existing map[string]models.DealTranslation) string {
+	var b strings.Builder
+	for _, l := range h.translationLanguages(co) {
+		tr := existing[l]
+		name := h.I18n.Name(l)
+		fmt.Fprintf(&b, "<fieldset><legend>%s</legend><input name='title_%s' placeholder='%s' value='%s'><textarea name='description_%s'>%s</textarea></fieldset>",
+			name, l, t("translation_title_placeholder", "language", name), template.HTMLEscapeString(tr.Title), l, template.HTMLEscapeString(tr.Description))
+	}
+	return b.String()
+}
+
+// formTranslations reads a deal form's translations for co's languages, with
+// d's own title and description as the default language row.
+func (h *Handler) formTranslations(c *fiber.Ctx, co *models.Country, d *models.Deal) []models.DealTranslation {
+	out := []models.DealTranslation{{Lang: co.DefaultLanguage, Title: d.Title, Description: d.Description}}
+	for _, l := range h.translationLanguages(co) {
+		out = append(out, models.DealTranslation{Lang: l, Title: c.FormValue("title_" + l), Description: c.FormValue("description_" + l)})
+	}
+	return out
+}
+
+func (h *Handler) TranslationInputs(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Query("country_id"), 10, 64)
+	co, err := h.Repo.CountryByID(c.Context(), id)
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	return c.Type("html").SendString(h.translationInputs(h.t(c), co, nil))
+}
+
+func (h *Handler) languageCheckboxes(selected []string) string {
+	var b strings.Builder
+	for _, l := range h.I18n.Languages() {
+		checked := ""
+		for _, s := range selected {
+			if s == l {
+				checked = " checked"
+			}
+		}
+		fmt.Fprintf(&b, "<label><input type='checkbox' name='languages' value='%s'%s> %s</label>", l, checked, h.I18n.Name(l))
+	}
+	return b.String()
+}
+
+func (h *Handler) formLanguages(c *fiber.Ctx) []string {
+	out := []string{}
+	for _, raw := range c.Context().PostArgs().PeekMulti("languages") {
+		if l := string(raw); h.I18n.Has(l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func (h *Handler) SaveCountryLanguages(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	if !c.Locals("user").(*models.User).InCountry(id) {
+		return fiber.ErrForbidden
+	}
+	fb, err := i18n.ParseFallbacks(c.FormValue("fallbacks"))
+	if err != nil {
+		return userError(400, h.t(c)("error_invalid_fallbacks", "error", err.Error()))
+	}
+	if err := h.Repo.SetCountryLanguages(c.Context(), id, h.formLanguages(c), fb.String()); err != nil {
+		return err
+	}
+	h.Service.ForgetCountries()
+	return c.Redirect("/admin/master")
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"go-next-cms/internal/i18n"
+	"go-next-cms/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func lockStatus(u models.User, csrf any, t i18n.Func) string {
+	if u.LockedUntil == nil {
+		if u.FailedLoginCount > 0 {
+			return fmt.Sprintf(" <small>%s</small>", t("failed_logins", "count", u.FailedLoginCount))
+		}
+		return ""
+	}
+	return fmt.Sprintf(" <strong>%s</strong> <form method='post' action='/admin/users/%d/unlock'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", t("locked_until", "time", u.LockedUntil.Format("2006-01-02 15:04")), u.ID, csrf, t("unlock"))
+}
+
+func (h *Handler) AdminUnlockUser(c *fiber.Ctx) error {
+	target, err := h.managedUser(c)
+	if err != nil {
+		return err
+	}
+	if err := h.Service.UnlockUser(c.Context(), target.ID); err != nil {
+		return err
+	}
+	return c.Redirect("/admin/users")
+}
+
+func (h *Handler) AdminLoginAudit(c *fiber.Ctx) error {
+	attempts, err := h.Service.LoginAudit(c.Context())
+	if err != nil {
+		return err
+	}
+	t := h.t(c)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>%s</h1><table><tr><th>%s</th><th>%s</th><th>%s</th><th>%s</th><th>%s</th></tr>", t("login_audit"), t("when"), t("email"), t("ip"), t("result"), t("user_agent"))
+	for _, a := range attempts {
+		result := t("login_succeeded")
+		if !a.Success {
+			result = t("login_failed", "reason", a.Reason)
+		}
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>", a.CreatedAt.Format("2006-01-02 15:04:05"),
+			template.HTMLEscapeString(a.Email), template.HTMLEscapeString(a.IP), template.HTMLEscapeString(result), template.HTMLEscapeString(a.UserAgent))
+	}
+	b.WriteString("</table>")
+	return h.render(c, t("login_audit"), h.lastCountry(c), template.HTML(b.String()))
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"go-next-cms/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// masterRow is one master data row offered for translation.
+type masterRow struct {
+	ID   int64
+	Name string
+}
+
+// masterTranslationForms renders a form per row of entity with a name input
+// for each bundle language, filled with the stored translations.
+func (h *Handler) masterTranslationForms(c *fiber.Ctx, entity models.MasterEntity, rows []masterRow) string {
+	t := h.t(c)
+	existing, _ := h.Repo.MasterTranslations(c.Context(), entity)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h3>%s</h3>", t("master_entity_name", "entity", string(entity)))
+	for _, row := range rows {
+		fmt.Fprintf(&b, "<form method='post' action='/admin/master/%s/%d/translations'><strong>%s</strong>", entity, row.ID, template.HTMLEscapeString(row.Name))
+		for _, l := range h.I18n.Languages() {
+			name := h.I18n.Name(l)
+			fmt.Fprintf(&b, "<input name='name_%s' placeholder='%s' value='%s'>", l, name, template.HTMLEscapeString(existing[row.ID][l]))
+		}
+		fmt.Fprintf(&b, "<input type='hidden' name='csrf' value='%s'><button>%s</button></form>", c.Locals("csrf"), t("save"))
+	}
+	return b.String()
+}
+
+// SaveMasterTranslations stores the per-language names of one master data
+// row; an empty input falls back to the row's own name.
+func (h *Handler) SaveMasterTranslations(c *fiber.Ctx) error {
+	entity := models.MasterEntity(c.Params("entity"))
+	if !entity.Valid() {
+		return fiber.ErrNotFound
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	ok, err := h.Repo.MasterExists(c.Context(), entity, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fiber.ErrNotFound
+	}
+	if entity == models.EntityCity {
+		city, err := h.Repo.CityByID(c.Context(), id)
+		if err != nil {
+			return err
+		}
+		if !c.Locals("user").(*models.User).InCountry(city.CountryID) {
+			return fiber.ErrForbidden
+		}
+	}
+	names := map[string]string{}
+	for _, l := range h.I18n.Languages() {
+		names[l] = strings.TrimSpace(c.FormValue("name_" + l))
+	}
+	if err := h.Repo.SetMasterTranslations(c.Context(), entity, id, names); err != nil {
+		return err
+	}
+	return c.Redirect("/admin/master")
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-next-cms/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handler) SessionList(c *fiber.Ctx) error {
+	u := c.Locals("user").(*models.User)
+	items, err := h.Repo.UserSessions(c.Context(), u.ID)
+	if err != nil {
+		return err
+	}
+	current := ""
+	if sess, err := h.Sessions.Get(c); err == nil {
+		current = sess.ID()
+	}
+	t := h.t(c)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>%s</h1><table><tr><th>%s</th><th>%s</th><th>%s</th><th>%s</th><th></th></tr>", t("active_sessions"), t("device"), t("ip"), t("signed_in"), t("last_seen"))
+	for _, s := range items {
+		action := fmt.Sprintf("<form method='post' action='/account/sessions/%d/revoke'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", s.ID, c.Locals("csrf"), t("revoke"))
+		if s.SID == current {
+			action = t("this_device")
+		}
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>", template.HTMLEscapeString(s.UserAgent), template.HTMLEscapeString(s.IP), s.CreatedAt.Format(time.DateTime), s.LastSeenAt.Format(time.DateTime), action)
+	}
+	b.WriteString("</table>")
+	return h.render(c, t("sessions"), h.lastCountry(c), template.HTML(b.String()))
+}
+
+func (h *Handler) RevokeSession(c *fiber.Ctx) error {
+	u := c.Locals("user").(*models.User)
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err := h.Repo.DeleteUserSession(c.Context(), u.ID, id); err != nil {
+		return err
+	}
+	return c.Redirect("/account/sessions")
+}
+
+func (h *Handler) AdminForceLogout(c *fiber.Ctx) error {
+	target, err := h.managedUser(c)
+	if err != nil {
+		return err
+	}
+	if err := h.Repo.DeleteUserSessions(c.Context(), target.ID); err != nil {
+		return err
+	}
+	return c.Redirect("/admin/users")
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-next-cms/internal/auth"
+	"go-next-cms/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handler) AdminUserTokens(c *fiber.Ctx) error {
+	return h.renderUserTokens(c, "")
+}
+
+func (h *Handler) renderUserTokens(c *fiber.Ctx, created string) error {
+	u, err := h.managedUser(c)
+	if err != nil {
+		return err
+	}
+	id := u.ID
+	tokens, err := h.Repo.APITokensByUser(c.Context(), id)
+	if err != nil {
+		return err
+	}
+	csrf := c.Locals("csrf").(string)
+	t := h.t(c)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>%s</h1>", t("api_tokens_for", "email", template.HTMLEscapeString(u.Email)))
+	if created != "" {
+		fmt.Fprintf(&b, "<p class='notice'>%s <code>%s</code></p>", t("new_token"), created)
+	}
+	fmt.Fprintf(&b, "<form method='post' action='/admin/users/%d/tokens'><input name='name' placeholder='%s'>", id, t("name_placeholder"))
+	for _, sc := range models.APIScopes {
+		fmt.Fprintf(&b, "<label><input type='checkbox' name='scopes' value='%s'> %s</label>", sc, sc)
+	}
+	fmt.Fprintf(&b, "<input name='rate_limit' type='number' value='60' title='%s'><input name='expires_days' type='number' placeholder='%s'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", t("requests_per_minute"), t("expires_in_days"), csrf, t("create"))
+	fmt.Fprintf(&b, "<table><tr><th>%s</th><th>%s</th><th>%s</th><th>%s</th><th>%s</th><th>%s</th><th></th></tr>", t("name"), t("token_prefix"), t("token_scopes"), t("token_limit"), t("token_expires"), t("token_last_used"))
+	for _, tok := range tokens {
+		scopes := make([]string, 0, len(tok.Scopes))
+		for _, sc := range tok.Scopes {
+			scopes = append(scopes, string(sc))
+		}
+		action := t("token_revoked")
+		if tok.RevokedAt == nil {
+			action = fmt.Sprintf("<form method='post' action='/admin/users/%d/tokens/%d/revoke'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", id, tok.ID, csrf, t("revoke"))
+		}
+		fmt.Fprintf(&b, "<tr><td>%s</td><td><code>dl_%s</code></td><td>%s</td><td>%d</td><td>%s</td><td>%s</td><td>%s</td></tr>", template.HTMLEscapeString(tok.Name), tok.Prefix, strings.Join(scopes, ", "), tok.RateLimit, formatOptionalTime(tok.ExpiresAt), formatOptionalTime(tok.LastUsedAt), action)
+	}
+	b.WriteString("</table>")
+	return h.render(c, t("api_tokens"), h.lastCountry(c), template.HTML(b.String()))
+}
+
+func (h *Handler) CreateAPIToken(c *fiber.Ctx) error {
+	target, err := h.managedUser(c)
+	if err != nil {
+		return err
+	}
+	id := target.ID
+	raw, prefix, hash, err := auth.GenerateAPIToken()
+	if err != nil {
+		return err
+	}
+	t := &models.APIToken{UserID: id, Name: c.FormValue("name"), Prefix: prefix, TokenHash: hash, RateLimit: 60}
+	if n, err := strconv.Atoi(c.FormValue("rate_limit")); err == nil && n > 0 {
+		t.RateLimit = n
+	}
+	if days, err := strconv.Atoi(c.FormValue("expires_days")); err == nil && days > 0 {
+		exp := time.Now().AddDate(0, 0, days)
+		t.ExpiresAt = &exp
+	}
+	for _, v := range c.Context().PostArgs().PeekMulti("scopes") {
+		for _, sc := range models.APIScopes {
+			if string(v) == string(sc) {
+				t.Scopes = append(t.Scopes, sc)
+			}
+		}
+	}
+	if err := h.Repo.CreateAPIToken(c.Context(), t); err != nil {
+		return err
+	}
+	return h.renderUserTokens(c, raw)
+}
+
+func (h *Handler) RevokeAPIToken(c *fiber.Ctx) error {
+	target, err := h.managedUser(c)
+	if err != nil {
+		return err
+	}
+	tokenID, _ := strconv.ParseInt(c.Params("tokenID"), 10, 64)
+	if err := h.Repo.RevokeAPIToken(c.Context(), target.ID, tokenID); err != nil {
+		return err
+	}
+	return c.Redirect(fmt.Sprintf("/admin/users/%d/tokens", target.ID))
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.DateTime)
+}
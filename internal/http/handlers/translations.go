@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const translationPageSize = 50
+
+// AdminTranslations is the translator queue: deals whose translation into a
+// language of their country is missing, a draft or machine-made, filtered by
+// ?target= language, ?status= and ?country=, translationPageSize per ?page=.
+func (h *Handler) AdminTranslations(c *fiber.Ctx) error {
+	ids, filter, err := h.adminCountryFilter(c, "/admin/translations")
+	if err != nil {
+		return err
+	}
+	target := c.Query("target")
+	if !h.I18n.Has(target) {
+		target = ""
+	}
+	status := models.TranslationStatus(c.Query("status"))
+	if !status.Valid() || status == models.TranslationReviewed {
+		status = ""
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	tasks, total, err := h.Repo.TranslationQueue(c.Context(), ids, h.I18n.Languages(), target, status, page, translationPageSize)
+	if err != nil {
+		return err
+	}
+	t := h.t(c)
+	link := func(key, value string) string {
+		q := url.Values{}
+		for k, v := range map[string]string{"country": c.Query("country"), "target": target, "status": string(status)} {
+			if v != "" {
+				q.Set(k, v)
+			}
+		}
+		if value == "" || key == "page" && value == "1" {
+			q.Del(key)
+		} else {
+			q.Set(key, value)
+		}
+		return "/admin/translations?" + q.Encode()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>%s</h1>%s<nav><a href='%s'>%s</a>", t("translation_queue"), filter, link("target", ""), t("all_languages"))
+	for _, l := range h.I18n.Languages() {
+		fmt.Fprintf(&b, " | <a href='%s'>%s</a>", link("target", l), h.I18n.Name(l))
+	}
+	fmt.Fprintf(&b, "</nav><nav><a href='%s'>%s</a>", link("status", ""), t("all_statuses"))
+	for _, s := range []models.TranslationStatus{models.TranslationMissing, models.TranslationDraft, models.TranslationMachine} {
+		fmt.Fprintf(&b, " | <a href='%s'>%s</a>", link("status", string(s)), t("translation_status", "status", string(s)))
+	}
+	b.WriteString("</nav>")
+	for _, x := range tasks {
+		meta := t("translation_status", "status", string(x.Status))
+		if x.AuthorName != "" {
+			meta += " · " + template.HTMLEscapeString(x.AuthorName)
+		}
+		if x.UpdatedAt != nil {
+			meta += " · " + x.UpdatedAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(&b, "<div>[%s] <b>%s</b> → %s (%s)<p>%s</p><form method='post' action='/admin/translations/%d/%s'><input name='title' value='%s'><textarea name='description'>%s</textarea><input type='hidden' name='csrf' value='%s'><button name='action' value='draft'>%s</button><button name='action' value='review'>%s</button></form></div>",
+			x.CountryCode, template.HTMLEscapeString(x.DealTitle), h.I18n.Name(x.Lang), meta, template.HTMLEscapeString(x.DealDescription),
+			x.DealID, x.Lang, template.HTMLEscapeString(x.Title), template.HTMLEscapeString(x.Description), c.Locals("csrf"), t("save_draft"), t("mark_reviewed"))
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintf(&b, "<p>%s</p>", t("no_translation_tasks"))
+	}
+	b.WriteString("<nav>")
+	if page > 1 {
+		fmt.Fprintf(&b, "<a href='%s'>%s</a> ", link("page", strconv.Itoa(page-1)), t("prev_page"))
+	}
+	if page*translationPageSize < total {
+		fmt.Fprintf(&b, "<a href='%s'>%s</a>", link("page", strconv.Itoa(page+1)), t("next_page"))
+	}
+	b.WriteString("</nav>")
+	return h.render(c, t("translations"), h.lastCountry(c), template.HTML(b.String()))
+}
+
+// AdminReviewTranslation saves a translation from the queue: as a draft by
+// the current user with action=draft, else as reviewed by them, which
+// service.ReviewTranslation may refuse.
+func (h *Handler) AdminReviewTranslation(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	d, err := h.Repo.DealByID(c.Context(), id)
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	u := c.Locals("user").(*models.User)
+	if !u.InCountry(d.CountryID) {
+		return fiber.ErrForbidden
+	}
+	country, err := h.Repo.CountryByID(c.Context(), d.CountryID)
+	if err != nil {
+		return err
+	}
+	// Only the languages the deal form offers for its country are
+	// translations; the default language is the deal's own text.
+	lang := c.Params("lang")
+	if !slices.Contains(h.translationLanguages(country), lang) {
+		return fiber.ErrNotFound
+	}
+	tr := models.DealTranslation{DealID: id, Lang: lang, Title: strings.TrimSpace(c.FormValue("title")), Description: c.FormValue("description")}
+	if tr.Title == "" {
+		return userError(400, h.t(c)("error_translation_title"))
+	}
+	if c.FormValue("action") == "draft" {
+		err = h.Repo.SaveTranslationDraft(c.Context(), tr, u.ID)
+	} else {
+		err = h.Service.ReviewTranslation(c.Context(), u, d.CountryID, tr)
+	}
+	if errors.Is(err, service.ErrOwnTranslation) {
+		return userError(fiber.StatusConflict, h.t(c)("error_own_translation"))
+	}
+	if err != nil {
+		return err
+	}
+	return c.Redirect("/admin/translations")
+}
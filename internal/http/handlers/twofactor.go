@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-next-cms/internal/http/middleware"
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	pendingUIDKey     = "pending_uid"
+	pendingAtKey      = "pending_at"
+	pendingSecretKey  = "totp_pending_secret"
+	secondFactorGrace = 5 * time.Minute
+)
+
+// beginSecondFactor parks the password-verified user in the session until a
+// TOTP or recovery code is supplied on /account/login/2fa.
+func (h *Handler) beginSecondFactor(c *fiber.Ctx, u *models.User) error {
+	sess, err := h.Sessions.Get(c)
+	if err != nil {
+		return err
+	}
+	sess.Set(pendingUIDKey, strconv.FormatInt(u.ID, 10))
+	sess.Set(pendingAtKey, time.Now().Unix())
+	if err := sess.Save(); err != nil {
+		return err
+	}
+	return c.Redirect("/account/login/2fa")
+}
+
+// pendingUser is the user parked by beginSecondFactor, else the signed-in
+// user when TOTP is enrolled but has not been passed in this session.
+func (h *Handler) pendingUser(c *fiber.Ctx) (*models.User, error) {
+	if u, ok := c.Locals("user").(*models.User); ok && u.TOTPEnabledAt != nil && !middleware.SecondFactorVerified(c) {
+		return u, nil
+	}
+	sess, err := h.Sessions.Get(c)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := sess.Get(pendingUIDKey).(string)
+	at, _ := sess.Get(pendingAtKey).(int64)
+	if raw == "" || time.Since(time.Unix(at, 0)) > secondFactorGrace {
+		return nil, errors.New("no pending login")
+	}
+	id, _ := strconv.ParseInt(raw, 10, 64)
+	return h.Repo.UserByID(c.Context(), id)
+}
+
+func (h *Handler) SecondFactorForm(c *fiber.Ctx) error {
+	if _, err := h.pendingUser(c); err != nil {
+		return c.Redirect("/account/login")
+	}
+	t := h.t(c)
+	body := fmt.Sprintf("<h1>%s</h1><form method='post' action='/account/login/2fa'><input name='code' autocomplete='one-time-code' placeholder='%s'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", t("two_factor"), t("second_factor_placeholder"), c.Locals("csrf"), t("verify"))
+	return h.render(c, t("two_factor"), h.lastCountry(c), template.HTML(body))
+}
+
+func (h *Handler) SecondFactor(c *fiber.Ctx) error {
+	u, err := h.pendingUser(c)
+	if err != nil {
+		return c.Redirect("/account/login")
+	}
+	if err := h.Service.SecondFactorLogin(c.Context(), u, c.FormValue("code"), c.IP(), c.Get(fiber.HeaderUserAgent)); err != nil {
+		if errors.Is(err, service.ErrInvalidCode) {
+			return userError(400, h.t(c)("error_invalid_code"))
+		}
+		return h.loginError(c, err)
+	}
+	return h.startSession(c, u)
+}
+
+func (h *Handler) TwoFactorSettings(c *fiber.Ctx) error {
+	u := c.Locals("user").(*models.User)
+	csrf := c.Locals("csrf").(string)
+	t := h.t(c)
+	if u.TOTPEnabledAt != nil {
+		left, err := h.Repo.RemainingRecoveryCodes(c.Context(), u.ID)
+		if err != nil {
+			return err
+		}
+		body := fmt.Sprintf("<h1>%s</h1><p>%s</p><form method='post' action='/account/2fa/disable'><input name='code' placeholder='%s'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", t("two_factor"), t("two_factor_status", "since", u.TOTPEnabledAt.Format(time.DateOnly), "left", left), t("current_code"), csrf, t("disable"))
+		return h.render(c, t("two_factor"), h.lastCountry(c), template.HTML(body))
+	}
+	secret, uri, err := service.TOTPProvisioning(u)
+	if err != nil {
+		return err
+	}
+	qr, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return err
+	}
+	sess, err := h.Sessions.Get(c)
+	if err != nil {
+		return err
+	}
+	sess.Set(pendingSecretKey, secret)
+	if err := sess.Save(); err != nil {
+		return err
+	}
+	body := fmt.Sprintf("<h1>%s</h1><p>%s</p><p><img class='qr' src='data:image/png;base64,%s' width='256' height='256' alt='%s'></p><p><a href='%s'><code>%s</code></a></p><p>%s <code>%s</code></p><form method='post' action='/account/2fa'><input name='code' autocomplete='one-time-code' placeholder='123456'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>",
+		t("two_factor"), t("two_factor_setup"), base64.StdEncoding.EncodeToString(qr), t("two_factor_qr"), template.HTMLEscapeString(uri), template.HTMLEscapeString(uri), t("two_factor_secret"), secret, csrf, t("enable"))
+	return h.render(c, t("two_factor"), h.lastCountry(c), template.HTML(body))
+}
+
+func (h *Handler) EnableTwoFactor(c *fiber.Ctx) error {
+	u := c.Locals("user").(*models.User)
+	sess, err := h.Sessions.Get(c)
+	if err != nil {
+		return err
+	}
+	secret, _ := sess.Get(pendingSecretKey).(string)
+	if secret == "" {
+		return c.Redirect("/account/2fa")
+	}
+	codes, err := h.Service.EnableTOTP(c.Context(), u, secret, c.FormValue("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCode):
+			return userError(400, h.t(c)("error_invalid_code"))
+		case errors.Is(err, service.ErrNoTOTPKey):
+			return userError(fiber.StatusServiceUnavailable, h.t(c)("error_two_factor_unavailable"))
+		}
+		return err
+	}
+	sess.Delete(pendingSecretKey)
+	// The code just checked counts as this session's second factor.
+	sess.Set(middleware.SecondFactorKey, true)
+	if err := sess.Save(); err != nil {
+		return err
+	}
+	t := h.t(c)
+	body := fmt.Sprintf("<h1>%s</h1><p>%s</p><pre>%s</pre><p><a href='/admin'>%s</a></p>", t("two_factor_enabled"), t("recovery_codes_help"), strings.Join(codes, "\n"), t("continue"))
+	return h.render(c, t("two_factor"), h.lastCountry(c), template.HTML(body))
+}
+
+func (h *Handler) DisableTwoFactor(c *fiber.Ctx) error {
+	u := c.Locals("user").(*models.User)
+	if err := h.Service.DisableTOTP(c.Context(), u, c.FormValue("code")); err != nil {
+		if errors.Is(err, service.ErrInvalidCode) {
+			return userError(400, h.t(c)("error_invalid_code"))
+		}
+		return err
+	}
+	return c.Redirect("/account/2fa")
+}
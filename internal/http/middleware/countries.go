@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"errors"
+
+	"go-next-cms/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// KnownCountry rejects /:countryCode routes whose code is not in the
+// countries table with a 404, and stores the country in the "country" local.
+func KnownCountry(s *service.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		co, err := s.Country(c.Context(), c.Params("countryCode"))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fiber.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		c.Locals("country", co)
+		return c.Next()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"strconv"
 
+	"go-next-cms/internal/config"
 	"go-next-cms/internal/models"
 	"go-next-cms/internal/repo"
 
@@ -14,13 +15,25 @@ import (
 )
 
 func StructuredLogger() fiber.Handler {
-	return logger.New(logger.Config{Format: "{\"time\":\"${time}\",\"status\":${status},\"latency\":${latency},\"method\":\"${method}\",\"path\":\"${path}\"}\n"})
+	return logger.New(logger.Config{Format: "{\"time\":\"${time}\",\"status\":${status},\"latency\":${latency},\"method\":\"${method}\",\"path\":\"${path}\",\"request_id\":\"${locals:requestid}\"}\n"})
 }
 
-func SessionStore() *session.Store {
-	return session.New(session.Config{CookieHTTPOnly: true, CookieSameSite: "lax"})
+func SessionStore(cfg config.Config, storage fiber.Storage) *session.Store {
+	return session.New(session.Config{
+		Storage:        storage,
+		Expiration:     cfg.SessionTTL,
+		KeyLookup:      "cookie:" + cfg.SessionCookieName,
+		CookiePath:     "/",
+		CookieSecure:   cfg.SessionCookieSecure,
+		CookieHTTPOnly: true,
+		CookieSameSite: "lax",
+	})
 }
 
+// SecondFactorKey is the session key set once the session's user has passed
+// TOTP or a recovery code, at login or while enrolling.
+const SecondFactorKey = "2fa_verified"
+
 func AttachUser(store *session.Store, repo *repo.Repository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		s, _ := store.Get(c)
@@ -32,11 +45,19 @@ func AttachUser(store *session.Store, repo *repo.Repository) fiber.Handler {
 		u, err := repo.UserByID(c.Context(), id)
 		if err == nil {
 			c.Locals("user", u)
+			c.Locals(SecondFactorKey, s.Get(SecondFactorKey) != nil)
 		}
 		return c.Next()
 	}
 }
 
+// SecondFactorVerified reports whether the session's user has passed the
+// second factor in this session.
+func SecondFactorVerified(c *fiber.Ctx) bool {
+	ok, _ := c.Locals(SecondFactorKey).(bool)
+	return ok
+}
+
 func RequireAuth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		u := c.Locals("user")
@@ -47,11 +68,43 @@ func RequireAuth() fiber.Handler {
 	}
 }
 
-func RequireAdmin() fiber.Handler {
+// RequireStaff lets through users whose role grants any permission. Staff
+// with TOTP enrolled whose session has not passed it, such as one signed in
+// before enrolling elsewhere, are sent to /account/login/2fa. With require2FA
+// set, staff who have not enrolled TOTP are sent to /account/2fa first.
+func RequireStaff(require2FA bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		u, ok := c.Locals("user").(*models.User)
+		if !ok || !u.Role.IsStaff() {
+			return fiber.ErrForbidden
+		}
+		switch {
+		case u.TOTPEnabledAt != nil && !SecondFactorVerified(c):
+			return c.Redirect("/account/login/2fa")
+		case require2FA && u.TOTPEnabledAt == nil:
+			return c.Redirect("/account/2fa")
+		}
+		return c.Next()
+	}
+}
+
+func RequirePermission(p models.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		u, _ := c.Locals("user").(*models.User)
+		if !u.Can(p) {
+			return fiber.ErrForbidden
+		}
+		return c.Next()
+	}
+}
+
+// RequireVerifiedEmail sends users without a confirmed email address to
+// /account/verify. It is a no-op unless enabled.
+func RequireVerifiedEmail(enabled bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		u, ok := c.Locals("user").(*models.User)
-		if !ok || u.Role != models.RoleAdmin {
-			return c.SendStatus(fiber.StatusForbidden)
+		if enabled && ok && u.EmailVerifiedAt == nil {
+			return c.Redirect("/account/verify")
 		}
 		return c.Next()
 	}
@@ -73,7 +126,7 @@ func CSRFMiddleware(store *session.Store) fiber.Handler {
 		}
 		if c.Method() == fiber.MethodPost {
 			if c.FormValue("csrf") != c.Locals("csrf") {
-				return c.Status(fiber.StatusBadRequest).SendString("CSRF mismatch")
+				return fiber.NewError(fiber.StatusBadRequest, "CSRF mismatch")
 			}
 		}
 		return c.Next()
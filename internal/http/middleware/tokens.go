@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-next-cms/internal/auth"
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/repo"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func apiError(c *fiber.Ctx, status int, code, msg string) error {
+	return c.Status(status).JSON(fiber.Map{"error": fiber.Map{"code": code, "message": msg}})
+}
+
+// BearerAuth authenticates `Authorization: Bearer` API tokens into the same
+// "user" local as the session cookie and stores the token under "api_token".
+// Requests without a bearer header pass through untouched.
+func BearerAuth(r *repo.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		raw, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !found {
+			return c.Next()
+		}
+		raw = strings.TrimSpace(raw)
+		prefix, ok := auth.APITokenPrefix(raw)
+		if !ok {
+			return apiError(c, fiber.StatusUnauthorized, "invalid_token", "malformed API token")
+		}
+		t, err := r.APITokenByPrefix(c.Context(), prefix)
+		if err != nil || !auth.CheckAPIToken(t.TokenHash, raw) {
+			return apiError(c, fiber.StatusUnauthorized, "invalid_token", "unknown API token")
+		}
+		if t.RevokedAt != nil || (t.ExpiresAt != nil && t.ExpiresAt.Before(time.Now())) {
+			return apiError(c, fiber.StatusUnauthorized, "invalid_token", "API token revoked or expired")
+		}
+		u, err := r.UserByID(c.Context(), t.UserID)
+		if err != nil {
+			return apiError(c, fiber.StatusUnauthorized, "invalid_token", "token owner not found")
+		}
+		_ = r.TouchAPIToken(c.Context(), t.ID)
+		c.Locals("user", u)
+		c.Locals("api_token", t)
+		return c.Next()
+	}
+}
+
+// RequireScope rejects token-authenticated requests whose token lacks scope.
+// Session and anonymous requests are left to the route's own checks.
+func RequireScope(scope models.APIScope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		t, ok := c.Locals("api_token").(*models.APIToken)
+		if ok && !slices.Contains(t.Scopes, scope) {
+			return apiError(c, fiber.StatusForbidden, "insufficient_scope", "token lacks scope "+string(scope))
+		}
+		return c.Next()
+	}
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// APIRateLimit applies each token's per-minute limit, and anonPerMinute per
+// client IP to requests without a token, with a fixed window, and reports
+// the limit through the RateLimit-* headers.
+func APIRateLimit(anonPerMinute int) fiber.Handler {
+	var mu sync.Mutex
+	windows := map[string]*rateWindow{}
+	return func(c *fiber.Ctx) error {
+		key, limit := "ip:"+c.IP(), anonPerMinute
+		if t, ok := c.Locals("api_token").(*models.APIToken); ok {
+			key, limit = "token:"+strconv.FormatInt(t.ID, 10), t.RateLimit
+		}
+		now := time.Now()
+		mu.Lock()
+		w := windows[key]
+		if w == nil || now.Sub(w.start) >= time.Minute {
+			if len(windows) > 1024 {
+				for k, old := range windows {
+					if now.Sub(old.start) >= time.Minute {
+						delete(windows, k)
+					}
+				}
+			}
+			w = &rateWindow{start: now}
+			windows[key] = w
+		}
+		w.count++
+		count, reset := w.count, w.start.Add(time.Minute).Sub(now)
+		mu.Unlock()
+
+		resetSecs := strconv.Itoa(int(reset.Seconds()) + 1)
+		remaining := limit - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.Set("RateLimit-Limit", strconv.Itoa(limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Set("RateLimit-Reset", resetSecs)
+		if count > limit {
+			c.Set(fiber.HeaderRetryAfter, resetSecs)
+			return apiError(c, fiber.StatusTooManyRequests, "rate_limited", "rate limit exceeded")
+		}
+		return c.Next()
+	}
+}
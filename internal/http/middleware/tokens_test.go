@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"go-next-cms/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAPIRateLimit(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if c.Get("X-Token") != "" {
+			c.Locals("api_token", &models.APIToken{ID: 1, RateLimit: 3})
+		}
+		return c.Next()
+	})
+	app.Use(APIRateLimit(2))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	get := func(token string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		if token != "" {
+			req.Header.Set("X-Token", token)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	for i, want := range []int{200, 200, 429} {
+		if got := get(""); got != want {
+			t.Errorf("anonymous request %d: status %d, want %d", i+1, got, want)
+		}
+	}
+	for i, want := range []int{200, 200, 200, 429} {
+		if got := get("x"); got != want {
+			t.Errorf("token request %d: status %d, want %d", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fallbacks maps a language to the languages whose content stands in for it,
+// best first. Written as comma-separated chains, e.g. "ta:en, si:ta:en".
+type Fallbacks map[string][]string
+
+// ParseFallbacks parses chains like "ta:en, si:ta:en"; each chain gives the
+// fallbacks of its first language.
+func ParseFallbacks(s string) (Fallbacks, error) {
+	out := Fallbacks{}
+	for _, chain := range strings.Split(s, ",") {
+		chain = strings.TrimSpace(chain)
+		if chain == "" {
+			continue
+		}
+		langs := strings.Split(chain, ":")
+		if len(langs) < 2 {
+			return nil, fmt.Errorf("fallback %q: want lang:fallback", chain)
+		}
+		for i, l := range langs {
+			l = strings.ToLower(strings.TrimSpace(l))
+			if !validLang(l) {
+				return nil, fmt.Errorf("fallback %q: bad language %q", chain, l)
+			}
+			langs[i] = l
+		}
+		out[langs[0]] = langs[1:]
+	}
+	return out, nil
+}
+
+func (f Fallbacks) String() string {
+	chains := make([]string, 0, len(f))
+	for l, next := range f {
+		chains = append(chains, strings.Join(append([]string{l}, next...), ":"))
+	}
+	sort.Strings(chains)
+	return strings.Join(chains, ", ")
+}
+
+// Chain lists the languages to look for content in when showing lang, best
+// first. The fallbacks of country override the bundle's Fallbacks language by
+// language, and are followed transitively: with ta:si and si:en, ta gives
+// ta, si, en.
+func (b *Bundle) Chain(lang string, country Fallbacks) []string {
+	out := []string{lang}
+	seen := map[string]bool{lang: true}
+	for i := 0; i < len(out); i++ {
+		next, ok := country[out[i]]
+		if !ok {
+			next = b.Fallbacks[out[i]]
+		}
+		for _, l := range next {
+			if !seen[l] {
+				seen[l] = true
+				out = append(out, l)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFallbacks(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Fallbacks
+		wantErr bool
+	}{
+		{"", Fallbacks{}, false},
+		{"ta:en", Fallbacks{"ta": {"en"}}, false},
+		{" TA : en , si:ta:en ,", Fallbacks{"ta": {"en"}, "si": {"ta", "en"}}, false},
+		{"haw:en", Fallbacks{"haw": {"en"}}, false},
+		{"ta", nil, true},
+		{"ta:", nil, true},
+		{"ta:english", nil, true},
+		{"pt-BR:pt", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFallbacks(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseFallbacks(%q): want error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseFallbacks(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFallbacks(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFallbacksString(t *testing.T) {
+	fb := Fallbacks{"ta": {"si", "en"}, "si": {"en"}}
+	if got, want := fb.String(), "si:en, ta:si:en"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	back, err := ParseFallbacks(fb.String())
+	if err != nil || !reflect.DeepEqual(back, fb) {
+		t.Errorf("ParseFallbacks(String()) = %v, %v; want %v", back, err, fb)
+	}
+}
+
+func TestChain(t *testing.T) {
+	b := &Bundle{Fallbacks: Fallbacks{"ta": {"en"}, "si": {"en"}}}
+	tests := []struct {
+		lang    string
+		country Fallbacks
+		want    []string
+	}{
+		{"en", nil, []string{"en"}},
+		{"ta", nil, []string{"ta", "en"}},
+		{"ta", Fallbacks{"ta": {"si"}}, []string{"ta", "si", "en"}},
+		{"ta", Fallbacks{"ta": {}}, []string{"ta"}},
+		{"ta", Fallbacks{"ta": {"si"}, "si": {"ta", "en"}}, []string{"ta", "si", "en"}},
+		{"fr", nil, []string{"fr"}},
+	}
+	for _, tt := range tests {
+		if got := b.Chain(tt.lang, tt.country); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Chain(%q, %v) = %v, want %v", tt.lang, tt.country, got, tt.want)
+		}
+	}
+}
@@ -2,19 +2,43 @@ package i18n
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
 type Bundle struct {
-	messages map[string]map[string]string
+	messages map[string]map[string]*message
+
+	// Debug logs each lookup that falls back to en or to the key, once per
+	// language and key.
+	Debug  bool
+	missed sync.Map
+
+	// Fallbacks are the default content fallback chains; see Chain.
+	Fallbacks Fallbacks
 }
 
+// Func translates key into a fixed language; see Bundle.T for args.
+type Func func(key string, args ...any) string
+
+// Load reads one <lang>.json per language from dir; the set of languages is
+// whatever files are there. Each file should define "language_name".
 func Load(dir string) (*Bundle, error) {
-	langs := []string{"en", "si", "ta"}
-	b := &Bundle{messages: map[string]map[string]string{}}
-	for _, lang := range langs {
-		file := filepath.Join(dir, lang+".json")
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{messages: map[string]map[string]*message{}}
+	for _, file := range files {
+		lang := strings.TrimSuffix(filepath.Base(file), ".json")
+		if !validLang(lang) {
+			return nil, fmt.Errorf("%s: language file names must be two or three lowercase letters", file)
+		}
 		data, err := os.ReadFile(file)
 		if err != nil {
 			return nil, err
@@ -23,23 +47,105 @@ func Load(dir string) (*Bundle, error) {
 		if err := json.Unmarshal(data, &m); err != nil {
 			return nil, err
 		}
-		b.messages[lang] = m
+		b.messages[lang] = map[string]*message{}
+		for k, v := range m {
+			msg, err := parseMessage(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", file, k, err)
+			}
+			b.messages[lang][k] = msg
+		}
+	}
+	if _, ok := b.messages["en"]; !ok {
+		return nil, fmt.Errorf("%s: en.json is required as the fallback language", dir)
 	}
 	return b, nil
 }
 
-func (b *Bundle) T(lang, key string) string {
-	if m, ok := b.messages[lang]; ok {
-		if v, ok := m[key]; ok {
-			return v
+// validLang reports whether lang is an ISO 639 code: two (639-1) or three
+// (639-2/3, for languages without a two-letter code) lowercase letters.
+func validLang(lang string) bool {
+	if len(lang) < 2 || len(lang) > 3 {
+		return false
+	}
+	for _, r := range lang {
+		if r < 'a' || r > 'z' {
+			return false
 		}
 	}
-	if m, ok := b.messages["en"]; ok {
-		if v, ok := m[key]; ok {
-			return v
+	return true
+}
+
+// T translates key into lang, falling back to en and then to the key itself.
+// args are name/value pairs for the message's arguments, as in
+// T("si", "deals_found", "count", 12).
+func (b *Bundle) T(lang, key string, args ...any) string {
+	msg, ok := b.messages[lang][key]
+	if !ok {
+		b.miss(lang, key)
+		lang = "en"
+		if msg, ok = b.messages[lang][key]; !ok {
+			b.miss(lang, key)
+			return key
 		}
 	}
-	return key
+	if len(args) == 0 && len(msg.parts) == 1 && msg.parts[0].text != "" {
+		return msg.parts[0].text
+	}
+	named := make(map[string]any, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		if name, ok := args[i].(string); ok {
+			named[name] = args[i+1]
+		}
+	}
+	var sb strings.Builder
+	format(&sb, msg.parts, lang, named, "")
+	return sb.String()
 }
 
-func Supported(lang string) bool { return lang == "en" || lang == "si" || lang == "ta" }
+func (b *Bundle) miss(lang, key string) {
+	if !b.Debug {
+		return
+	}
+	if _, seen := b.missed.LoadOrStore(lang+"\x00"+key, true); !seen {
+		log.Printf("i18n: %s has no message %q", lang, key)
+	}
+}
+
+// For returns T bound to lang.
+func (b *Bundle) For(lang string) Func {
+	return func(key string, args ...any) string { return b.T(lang, key, args...) }
+}
+
+func (b *Bundle) Languages() []string {
+	out := make([]string, 0, len(b.messages))
+	for lang := range b.messages {
+		out = append(out, lang)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (b *Bundle) Has(lang string) bool {
+	_, ok := b.messages[lang]
+	return ok
+}
+
+// Messages returns lang's messages as written in its file.
+func (b *Bundle) Messages(lang string) map[string]string {
+	out := make(map[string]string, len(b.messages[lang]))
+	for k, m := range b.messages[lang] {
+		out[k] = m.raw
+	}
+	return out
+}
+
+// Name is the language's name in that language, for pickers and labels.
+//
+//i18n:keys language_name
+func (b *Bundle) Name(lang string) string {
+	if m, ok := b.messages[lang]["language_name"]; ok {
+		return m.raw
+	}
+	return lang
+}
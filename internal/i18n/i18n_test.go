@@ -0,0 +1,43 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadLanguageFileNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"si", false},
+		{"haw", false},
+		{"engl", true},
+		{"e", true},
+		{"Si", true},
+		{"pt-BR", true},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, f := range []string{"en", tt.name} {
+			if err := os.WriteFile(filepath.Join(dir, f+".json"), []byte(`{"language_name": "x"}`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		b, err := Load(dir)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Load with %s.json: want error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Load with %s.json: %v", tt.name, err)
+			continue
+		}
+		if !b.Has(tt.name) {
+			t.Errorf("Load with %s.json: language not loaded", tt.name)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A message is a parsed ICU MessageFormat string. The supported subset is
+// {name} and {name, number} arguments, {n, plural, ...} with =N and CLDR
+// category selectors and # for the number, {x, select, ...}, and apostrophe
+// quoting: a doubled apostrophe is a literal one, and an apostrophe before a
+// brace quotes text up to the next apostrophe.
+type message struct {
+	raw   string
+	parts []part
+}
+
+// part is one of: literal text, an argument, a plural # or a choice between
+// sub-messages.
+type part struct {
+	text   string
+	arg    string
+	pound  bool
+	kind   string // "plural" or "select" for choices
+	offset float64
+	cases  map[string][]part
+}
+
+func parseMessage(s string) (*message, error) {
+	p := &parser{src: []rune(s)}
+	parts, err := p.message(0, false)
+	if err != nil {
+		return nil, err
+	}
+	return &message{raw: s, parts: parts}, nil
+}
+
+type parser struct {
+	src []rune
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peek() rune {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// word reads an argument name, type keyword or case selector.
+func (p *parser) word() string {
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n{},", p.src[p.pos]) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// message parses text up to the end of input, or up to the closing brace of
+// a choice case when depth > 0. inPlural makes # refer to the plural number.
+func (p *parser) message(depth int, inPlural bool) ([]part, error) {
+	var parts []part
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, part{text: text.String()})
+			text.Reset()
+		}
+	}
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		switch {
+		case r == '\'':
+			p.pos++
+			switch next := p.peek(); {
+			case next == '\'':
+				text.WriteRune('\'')
+				p.pos++
+			case next == '{' || next == '}' || (next == '#' && inPlural):
+				for p.pos < len(p.src) {
+					if p.src[p.pos] == '\'' {
+						if p.pos+1 < len(p.src) && p.src[p.pos+1] == '\'' {
+							text.WriteRune('\'')
+							p.pos += 2
+							continue
+						}
+						break
+					}
+					text.WriteRune(p.src[p.pos])
+					p.pos++
+				}
+				if p.pos >= len(p.src) {
+					return nil, p.errorf("unterminated quote")
+				}
+				p.pos++
+			default:
+				text.WriteRune('\'')
+			}
+		case r == '{':
+			flush()
+			p.pos++
+			arg, err := p.argument(depth)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, arg)
+		case r == '}':
+			if depth == 0 {
+				return nil, p.errorf("unmatched }")
+			}
+			flush()
+			return parts, nil
+		case r == '#' && inPlural:
+			flush()
+			parts = append(parts, part{pound: true})
+			p.pos++
+		default:
+			text.WriteRune(r)
+			p.pos++
+		}
+	}
+	if depth > 0 {
+		return nil, p.errorf("unterminated case")
+	}
+	flush()
+	return parts, nil
+}
+
+// argument parses what follows an opening brace, up to and including its
+// closing brace.
+func (p *parser) argument(depth int) (part, error) {
+	p.skipSpace()
+	name := p.word()
+	if name == "" {
+		return part{}, p.errorf("missing argument name")
+	}
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return part{arg: name}, nil
+	}
+	if p.peek() != ',' {
+		return part{}, p.errorf("expected , or } after %q", name)
+	}
+	p.pos++
+	p.skipSpace()
+	kind := p.word()
+	p.skipSpace()
+	switch kind {
+	case "number":
+		if p.peek() != '}' {
+			return part{}, p.errorf("number styles are not supported")
+		}
+		p.pos++
+		return part{arg: name}, nil
+	case "plural", "select":
+	default:
+		return part{}, p.errorf("unknown argument type %q", kind)
+	}
+	if p.peek() != ',' {
+		return part{}, p.errorf("expected , after %s", kind)
+	}
+	p.pos++
+	out := part{arg: name, kind: kind, cases: map[string][]part{}}
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.pos++
+			break
+		}
+		sel := p.word()
+		if sel == "" {
+			return part{}, p.errorf("expected a case in %s", name)
+		}
+		if v, ok := strings.CutPrefix(sel, "offset:"); ok && kind == "plural" {
+			off, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return part{}, p.errorf("bad offset %q", v)
+			}
+			out.offset = off
+			continue
+		}
+		p.skipSpace()
+		if p.peek() != '{' {
+			return part{}, p.errorf("expected { after case %q", sel)
+		}
+		p.pos++
+		sub, err := p.message(depth+1, kind == "plural")
+		if err != nil {
+			return part{}, err
+		}
+		p.pos++
+		out.cases[sel] = sub
+	}
+	if _, ok := out.cases["other"]; !ok {
+		return part{}, p.errorf("%s %q has no other case", kind, name)
+	}
+	return out, nil
+}
+
+// format renders parts with args in lang's plural rules. n is the number #
+// stands for inside a plural case.
+func format(b *strings.Builder, parts []part, lang string, args map[string]any, n string) {
+	for _, pt := range parts {
+		switch {
+		case pt.pound:
+			b.WriteString(n)
+		case pt.kind == "plural":
+			v, ok := args[pt.arg]
+			if !ok {
+				fmt.Fprintf(b, "{%s}", pt.arg)
+				continue
+			}
+			num := numberString(v)
+			if pt.offset != 0 {
+				if f, err := strconv.ParseFloat(num, 64); err == nil {
+					num = strconv.FormatFloat(f-pt.offset, 'f', -1, 64)
+				}
+			}
+			sub, ok := pt.cases["="+numberString(v)]
+			if !ok {
+				sub, ok = pt.cases[pluralCategory(lang, num)]
+			}
+			if !ok {
+				sub = pt.cases["other"]
+			}
+			format(b, sub, lang, args, num)
+		case pt.kind == "select":
+			sub, ok := pt.cases[fmt.Sprint(args[pt.arg])]
+			if !ok {
+				sub = pt.cases["other"]
+			}
+			format(b, sub, lang, args, n)
+		case pt.arg != "":
+			v, ok := args[pt.arg]
+			if !ok {
+				fmt.Fprintf(b, "{%s}", pt.arg)
+				continue
+			}
+			b.WriteString(numberString(v))
+		default:
+			b.WriteString(pt.text)
+		}
+	}
+}
+
+// numberString formats numbers plainly and anything else with fmt.
+func numberString(v any) string {
+	switch x := v.(type) {
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	}
+	return fmt.Sprint(v)
+}
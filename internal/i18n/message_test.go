@@ -0,0 +1,88 @@
+package i18n
+
+import "testing"
+
+// testBundle builds a bundle from messages keyed by language and key.
+func testBundle(t *testing.T, msgs map[string]map[string]string) *Bundle {
+	t.Helper()
+	b := &Bundle{messages: map[string]map[string]*message{}}
+	for lang, m := range msgs {
+		b.messages[lang] = map[string]*message{}
+		for k, v := range m {
+			msg, err := parseMessage(v)
+			if err != nil {
+				t.Fatalf("%s: %s: %v", lang, k, err)
+			}
+			b.messages[lang][k] = msg
+		}
+	}
+	return b
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	for _, s := range []string{
+		"{",
+		"{}",
+		"a }",
+		"{n, date}",
+		"{n, number, percent}",
+		"{n, plural, one {x}}",
+		"{n, plural, other {x}",
+		"{n, plural, offset:x other {x}}",
+		"{n, select other {x}}",
+		"{n, select, a x}",
+		"'{unterminated",
+	} {
+		if _, err := parseMessage(s); err == nil {
+			t.Errorf("parseMessage(%q): want error", s)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	b := testBundle(t, map[string]map[string]string{
+		"en": {
+			"hello":   "Hello, {name}!",
+			"count":   "{n, number} items",
+			"deals":   "{n, plural, =0 {No deals} one {# deal} other {# deals}}",
+			"guests":  "{n, plural, offset:1 =0 {Nobody} =1 {Only {host}} one {{host} and # other} other {{host} and # others}}",
+			"role":    "{role, select, admin {Administrator} other {User}}",
+			"nested":  "{role, select, admin {{n, plural, one {# admin} other {# admins}}} other {people}}",
+			"quoted":  "It''s '{literal}' and '#' {n, plural, other {'#' is #}}",
+			"only_en": "English",
+		},
+		"si": {
+			"deals": "{n, plural, one {# si-one} other {# si-other}}",
+		},
+	})
+	tests := []struct {
+		lang, key string
+		args      []any
+		want      string
+	}{
+		{"en", "hello", []any{"name", "Ana"}, "Hello, Ana!"},
+		{"en", "hello", nil, "Hello, {name}!"},
+		{"en", "count", []any{"n", 2.5}, "2.5 items"},
+		{"en", "deals", []any{"n", 0}, "No deals"},
+		{"en", "deals", []any{"n", 1}, "1 deal"},
+		{"en", "deals", []any{"n", 12}, "12 deals"},
+		{"en", "deals", nil, "{n}"},
+		{"si", "deals", []any{"n", 0}, "0 si-one"},
+		{"si", "deals", []any{"n", 2}, "2 si-other"},
+		{"en", "guests", []any{"n", 0, "host", "Ana"}, "Nobody"},
+		{"en", "guests", []any{"n", 1, "host", "Ana"}, "Only Ana"},
+		{"en", "guests", []any{"n", 2, "host", "Ana"}, "Ana and 1 other"},
+		{"en", "guests", []any{"n", 3, "host", "Ana"}, "Ana and 2 others"},
+		{"en", "role", []any{"role", "admin"}, "Administrator"},
+		{"en", "role", []any{"role", "guest"}, "User"},
+		{"en", "nested", []any{"role", "admin", "n", 2}, "2 admins"},
+		{"en", "quoted", []any{"n", 3}, "It's {literal} and '#' # is 3"},
+		{"si", "only_en", nil, "English"},
+		{"si", "missing", nil, "missing"},
+	}
+	for _, tt := range tests {
+		if got := b.T(tt.lang, tt.key, tt.args...); got != tt.want {
+			t.Errorf("T(%q, %q, %v) = %q, want %q", tt.lang, tt.key, tt.args, got, tt.want)
+		}
+	}
+}
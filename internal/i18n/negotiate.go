@@ -0,0 +1,46 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Negotiate picks the best of supported for an Accept-Language header. Tags
+// are tried in order of quality, and a region tag such as si-LK also matches
+// its base language. It returns "" when nothing acceptable is supported.
+func Negotiate(header string, supported []string) string {
+	type pref struct {
+		tag string
+		q   float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(f), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			prefs = append(prefs, pref{tag, q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	for _, p := range prefs {
+		base, _, _ := strings.Cut(p.tag, "-")
+		for _, s := range supported {
+			if s == p.tag || s == base {
+				return s
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,27 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{"en", "si", "ta"}
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{"ta", "ta"},
+		{"si-LK", "si"},
+		{"SI-lk, en;q=0.5", "si"},
+		{"en;q=0.4, ta;q=0.9", "ta"},
+		{"fr, de;q=0.8, si;q=0.1", "si"},
+		{"fr, de", ""},
+		{"*", ""},
+		{"ta;q=0, en;q=0.2", "en"},
+		{"ta;q=bogus, en;q=0.5", "ta"},
+		{"en, si", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header, supported); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
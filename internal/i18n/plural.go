@@ -0,0 +1,60 @@
+package i18n
+
+import "strings"
+
+// pluralRules are the CLDR cardinal rules of the bundled languages. They get
+// the CLDR operands of the number: i is the integer part, v the count of
+// visible fraction digits and f those digits as an integer. Languages without
+// rules only use "other".
+var pluralRules = map[string]func(i, v, f int) string{
+	// en: one is i = 1 and v = 0.
+	"en": func(i, v, f int) string {
+		if i == 1 && v == 0 {
+			return "one"
+		}
+		return "other"
+	},
+	// si: one is n = 0,1 or i = 0 and f = 1.
+	"si": func(i, v, f int) string {
+		if (i == 0 || i == 1) && f == 0 || i == 0 && f == 1 {
+			return "one"
+		}
+		return "other"
+	},
+	// ta: one is n = 1.
+	"ta": func(i, v, f int) string {
+		if i == 1 && f == 0 {
+			return "one"
+		}
+		return "other"
+	},
+}
+
+// pluralCategory returns the CLDR plural category of the decimal string num
+// in lang.
+func pluralCategory(lang, num string) string {
+	rule, ok := pluralRules[lang]
+	if !ok {
+		return "other"
+	}
+	num = strings.TrimPrefix(num, "-")
+	ip, fp, _ := strings.Cut(num, ".")
+	i, ok1 := atoi(ip)
+	f, ok2 := atoi(fp)
+	if !ok1 || !ok2 {
+		return "other"
+	}
+	return rule(i, len(fp), f)
+}
+
+// atoi parses a run of ASCII digits, treating "" as 0.
+func atoi(s string) (int, bool) {
+	n := 0
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+		n = n*10 + int(r-'0')
+	}
+	return n, true
+}
@@ -0,0 +1,32 @@
+package i18n
+
+import "testing"
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		lang, num, want string
+	}{
+		{"en", "1", "one"},
+		{"en", "0", "other"},
+		{"en", "2", "other"},
+		{"en", "1.0", "other"},
+		{"en", "-1", "one"},
+		{"si", "0", "one"},
+		{"si", "1", "one"},
+		{"si", "0.1", "one"},
+		{"si", "1.5", "other"},
+		{"si", "2", "other"},
+		{"ta", "1", "one"},
+		{"ta", "1.0", "one"},
+		{"ta", "0", "other"},
+		{"ta", "1.5", "other"},
+		{"en", "abc", "other"},
+		{"en", "1.x", "other"},
+		{"fr", "1", "other"},
+	}
+	for _, tt := range tests {
+		if got := pluralCategory(tt.lang, tt.num); got != tt.want {
+			t.Errorf("pluralCategory(%q, %q) = %q, want %q", tt.lang, tt.num, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-next-cms/internal/repo"
+	"go-next-cms/internal/service"
+	"go-next-cms/internal/storage"
+)
+
+const (
+	jobRunRetention = 30 * 24 * time.Hour
+	// uploadGrace spares fresh uploads whose deal may still be being saved.
+	uploadGrace = 24 * time.Hour
+)
+
+// DealJobs expire and publish deals through the workflow and clean up job
+// history and uploads no deal or revision uses.
+func DealJobs(s *service.Service, uploads storage.LocalUploader) []Job {
+	return []Job{
+		{Name: "expire_deals", Interval: time.Minute, Run: s.ExpireDeals},
+		{Name: "publish_scheduled_deals", Interval: time.Minute, Run: s.PublishScheduledDeals},
+		{Name: "cleanup", Interval: time.Hour, Run: func(ctx context.Context) error {
+			return cleanup(ctx, s.Repo, uploads)
+		}},
+	}
+}
+
+func cleanup(ctx context.Context, r *repo.Repository, uploads storage.LocalUploader) error {
+	runsErr := r.DeleteJobRunsBefore(ctx, time.Now().Add(-jobRunRetention))
+	used, err := r.ImageURLs(ctx)
+	if err != nil {
+		return errors.Join(runsErr, err)
+	}
+	return errors.Join(runsErr, uploads.Prune(used, time.Now().Add(-uploadGrace)))
+}
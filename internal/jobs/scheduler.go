@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go-next-cms/internal/repo"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	Repo   *repo.Repository
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(r *repo.Repository) *Scheduler { return &Scheduler{Repo: r} }
+
+func (s *Scheduler) Register(j Job) { s.jobs = append(s.jobs, j) }
+
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, j)
+	}
+}
+
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j Job) {
+	defer s.wg.Done()
+	t := time.NewTicker(j.Interval)
+	defer t.Stop()
+	for {
+		s.runOnce(ctx, j)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// runOnce executes j under a Postgres advisory lock so that only one instance
+// runs a given job at a time; instances that lose the race skip the tick.
+func (s *Scheduler) runOnce(ctx context.Context, j Job) {
+	started := time.Now()
+	var runErr error
+	ran, err := s.Repo.WithAdvisoryLock(ctx, "job:"+j.Name, func(ctx context.Context) error {
+		runErr = runJob(ctx, j)
+		return nil
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("job %s: lock: %v", j.Name, err)
+		}
+		return
+	}
+	if !ran {
+		return
+	}
+	if runErr != nil {
+		log.Printf("job %s: %v", j.Name, runErr)
+	}
+	if err := s.Repo.RecordJobRun(context.Background(), j.Name, started, time.Since(started), runErr); err != nil {
+		log.Printf("job %s: record run: %v", j.Name, err)
+	}
+}
+
+// runJob runs j, turning a panic into its error so one failing job neither
+// takes the server down nor goes unrecorded.
+func runJob(ctx context.Context, j Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("job %s: panic: %v\n%s", j.Name, p, debug.Stack())
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return j.Run(ctx)
+}
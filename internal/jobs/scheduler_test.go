@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunJob(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name string
+		run  func(context.Context) error
+		want string
+	}{
+		{"ok", func(context.Context) error { return nil }, ""},
+		{"error", func(context.Context) error { return boom }, "boom"},
+		{"panic", func(context.Context) error { panic("nil map") }, "panic: nil map"},
+	}
+	for _, tt := range tests {
+		err := runJob(context.Background(), Job{Name: tt.name, Run: tt.run})
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if !strings.Contains(got, tt.want) || (tt.want == "") != (err == nil) {
+			t.Errorf("%s: runJob error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
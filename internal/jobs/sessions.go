@@ -0,0 +1,20 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go-next-cms/internal/repo"
+)
+
+const loginAttemptRetention = 90 * 24 * time.Hour
+
+func SessionJobs(r *repo.Repository) []Job {
+	return []Job{
+		{Name: "session_gc", Interval: 10 * time.Minute, Run: r.DeleteExpiredSessions},
+		{Name: "user_token_gc", Interval: time.Hour, Run: r.DeleteStaleUserTokens},
+		{Name: "login_attempt_gc", Interval: time.Hour, Run: func(ctx context.Context) error {
+			return r.DeleteLoginAttemptsBefore(ctx, time.Now().Add(-loginAttemptRetention))
+		}},
+	}
+}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/repo"
+	"go-next-cms/internal/translate"
+)
+
+const (
+	translationBatch = 50
+	// A deal and language failing translationMaxAttempts times is left to
+	// translators; retries wait translationBackoff, doubling each time.
+	translationMaxAttempts = 5
+	translationBackoff     = 10 * time.Minute
+)
+
+// translationStore is the part of the repository draftTranslations uses.
+type translationStore interface {
+	MissingTranslations(ctx context.Context, langs []string, maxAttempts, limit int) ([]models.MissingTranslation, error)
+	SaveMachineTranslation(ctx context.Context, t models.DealTranslation) error
+	TranslationFailed(ctx context.Context, dealID int64, lang, reason string, backoff time.Duration) error
+}
+
+var _ translationStore = (*repo.Repository)(nil)
+
+// TranslationJobs drafts missing deal translations with p; langs are the
+// languages of countries that do not list their own.
+func TranslationJobs(r *repo.Repository, p translate.Provider, langs []string) []Job {
+	return []Job{
+		{Name: "draft_translations", Interval: 5 * time.Minute, Run: func(ctx context.Context) error {
+			return draftTranslations(ctx, r, p, langs)
+		}},
+	}
+}
+
+func draftTranslations(ctx context.Context, r translationStore, p translate.Provider, langs []string) error {
+	missing, err := r.MissingTranslations(ctx, langs, translationMaxAttempts, translationBatch)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, m := range missing {
+		title, desc, err := translateDeal(ctx, p, m)
+		if err != nil {
+			if ctx.Err() != nil {
+				return errors.Join(append(errs, err)...)
+			}
+			errs = append(errs, fmt.Errorf("deal %d %s: %w", m.DealID, m.To, err))
+			if err := r.TranslationFailed(ctx, m.DealID, m.To, err.Error(), translationBackoff); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+			continue
+		}
+		if err := r.SaveMachineTranslation(ctx, models.DealTranslation{DealID: m.DealID, Lang: m.To, Title: title, Description: desc}); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// translateDeal translates the title and, when there is one, the description
+// of m.
+func translateDeal(ctx context.Context, p translate.Provider, m models.MissingTranslation) (title, desc string, err error) {
+	title, err = p.Translate(ctx, m.Title, m.From, m.To)
+	if err == nil && title == "" {
+		err = errors.New("empty translation")
+	}
+	if err != nil || m.Description == "" {
+		return title, "", err
+	}
+	desc, err = p.Translate(ctx, m.Description, m.From, m.To)
+	return title, desc, err
+}
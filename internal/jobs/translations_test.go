@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/translate"
+)
+
+type fakeStore struct {
+	missing []models.MissingTranslation
+	saved   []models.DealTranslation
+	failed  []string
+}
+
+func (s *fakeStore) MissingTranslations(_ context.Context, _ []string, maxAttempts, limit int) ([]models.MissingTranslation, error) {
+	if maxAttempts != translationMaxAttempts || limit != translationBatch {
+		return nil, errors.New("unexpected limits")
+	}
+	return s.missing, nil
+}
+
+func (s *fakeStore) SaveMachineTranslation(_ context.Context, t models.DealTranslation) error {
+	s.saved = append(s.saved, t)
+	return nil
+}
+
+func (s *fakeStore) TranslationFailed(_ context.Context, _ int64, lang, _ string, backoff time.Duration) error {
+	if backoff != translationBackoff {
+		return errors.New("unexpected backoff")
+	}
+	s.failed = append(s.failed, lang)
+	return nil
+}
+
+func TestDraftTranslations(t *testing.T) {
+	store := &fakeStore{missing: []models.MissingTranslation{
+		{DealID: 1, From: "en", To: "si", Title: "pizza", Description: "free pizza"},
+		{DealID: 2, From: "en", To: "si", Title: "pizza"},
+		{DealID: 3, From: "en", To: "si", Title: "unknown"},
+		{DealID: 4, From: "en", To: "si", Title: "pizza", Description: "unknown"},
+		{DealID: 5, From: "en", To: "ta", Title: "pizza"},
+	}}
+	p := translate.Dictionary{Entries: map[string]map[string]string{"si": {"pizza": "පීසා", "free": "නොමිලේ"}}}
+	err := draftTranslations(context.Background(), store, p, nil)
+	if err == nil {
+		t.Error("draftTranslations: want the failures reported")
+	}
+	want := []models.DealTranslation{
+		{DealID: 1, Lang: "si", Title: "පීසා", Description: "නොමිලේ පීසා"},
+		{DealID: 2, Lang: "si", Title: "පීසා"},
+	}
+	if len(store.saved) != len(want) {
+		t.Fatalf("saved %v, want %v", store.saved, want)
+	}
+	for i := range want {
+		if store.saved[i] != want[i] {
+			t.Errorf("saved[%d] = %+v, want %+v", i, store.saved[i], want[i])
+		}
+	}
+	if got := len(store.failed); got != 3 {
+		t.Errorf("recorded %d failures, want 3", got)
+	}
+}
@@ -0,0 +1,104 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one connection on a local listener and answers it with
+// handle. It returns the listener's host and port.
+func fakeSMTP(t *testing.T, handle func(net.Conn)) (string, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	got := make(chan string, 1)
+	host, port := fakeSMTP(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 fake ready")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					reply("250 queued")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				reply("250 ok")
+			case "DATA":
+				inData = true
+				reply("354 go ahead")
+			case "QUIT":
+				reply("221 bye")
+				got <- data.String()
+				return
+			default:
+				reply("502 unknown")
+			}
+		}
+	})
+
+	m := SMTPMailer{Host: host, Port: port, From: "cms@example.com"}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Send(ctx, Message{To: "user@example.com", Subject: "Hello", Body: "line one\nline two\n"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	body := <-got
+	for _, want := range []string{"To: user@example.com\r\n", "Subject: Hello\r\n", "line one\r\nline two\r\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("message %q does not contain %q", body, want)
+		}
+	}
+}
+
+func TestSMTPMailerSendHonoursContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	host, port := fakeSMTP(t, func(net.Conn) { <-release })
+
+	m := SMTPMailer{Host: host, Port: port, From: "cms@example.com"}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- m.Send(ctx, Message{To: "user@example.com", Subject: "Hello"}) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Send = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send did not return after its context expired")
+	}
+}
@@ -5,10 +5,59 @@ import "time"
 type UserRole string
 
 const (
-	RoleSubmitter UserRole = "submitter"
-	RoleAdmin     UserRole = "admin"
+	RoleSubmitter  UserRole = "submitter"
+	RoleTranslator UserRole = "translator"
+	RoleModerator  UserRole = "moderator"
+	RoleAdmin      UserRole = "admin"
 )
 
+var UserRoles = []UserRole{RoleSubmitter, RoleTranslator, RoleModerator, RoleAdmin}
+
+type Permission string
+
+const (
+	PermModerate         Permission = "moderate"
+	PermManageUsers      Permission = "manage_users"
+	PermManageMasterData Permission = "manage_master_data"
+	PermEditConfig       Permission = "edit_config"
+	PermCreateFeatured   Permission = "create_featured"
+	PermTranslate        Permission = "translate"
+)
+
+var rolePermissions = map[UserRole][]Permission{
+	RoleTranslator: {PermTranslate},
+	RoleModerator:  {PermModerate, PermTranslate},
+	RoleAdmin:      {PermModerate, PermTranslate, PermManageUsers, PermManageMasterData, PermEditConfig, PermCreateFeatured},
+}
+
+func (r UserRole) Valid() bool {
+	for _, x := range UserRoles {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
+
+func (r UserRole) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+func (r UserRole) Can(p Permission) bool {
+	for _, x := range rolePermissions[r] {
+		if x == p {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaff reports whether the role grants any permission, i.e. whether
+// /admin is reachable at all.
+func (r UserRole) IsStaff() bool {
+	return len(rolePermissions[r]) > 0
+}
+
 type DealStatus string
 
 const (
@@ -25,6 +74,13 @@ type Country struct {
 	Code            string
 	Name            string
 	DefaultLanguage string
+	Languages       []string
+	// Fallbacks overrides LANG_FALLBACKS for the country's content, e.g.
+	// "si:en"; see i18n.ParseFallbacks.
+	Fallbacks string
+	// FallbackChains is Fallbacks parsed, as filled in by service.Country;
+	// nil when the country has none or they do not parse.
+	FallbackChains map[string][]string
 }
 
 type City struct {
@@ -55,6 +111,27 @@ type DealType struct {
 	Name string
 }
 
+// MasterEntity names a kind of master data row whose name can be translated.
+type MasterEntity string
+
+const (
+	EntityCity     MasterEntity = "city"
+	EntityCategory MasterEntity = "category"
+	EntityDealType MasterEntity = "deal_type"
+	EntityMerchant MasterEntity = "merchant"
+)
+
+var MasterEntities = []MasterEntity{EntityCity, EntityCategory, EntityDealType, EntityMerchant}
+
+func (e MasterEntity) Valid() bool {
+	for _, x := range MasterEntities {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
 type Deal struct {
 	ID              int64
 	Title           string
@@ -80,22 +157,269 @@ type Deal struct {
 	RejectionReason *string
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
+	ApprovedAt      *time.Time
+	PublishedAt     *time.Time
+	Snippet         string
+}
+
+// Search snippets wrap matched terms in these markers; views swap them for
+// <mark> after escaping the surrounding text.
+const (
+	SnippetMarkStart = "\u27ea"
+	SnippetMarkStop  = "\u27eb"
+)
+
+// DealStatusChange is the outcome of a workflow decision: the statuses the
+// deal passes through on its way from From to To, their side effects, and who
+// made the change for the status history.
+type DealStatusChange struct {
+	From            DealStatus
+	To              DealStatus
+	Path            []DealStatus
+	RejectionReason *string
+	ClearRejection  bool
+	StampApproved   bool
+	StampPublished  bool
+	Actor           string
+	UserID          *int64
+}
+
+// DealStatusEvent is one step of a deal's status history. UserName is empty
+// for changes made by the system.
+type DealStatusEvent struct {
+	ID        int64
+	DealID    int64
+	From      DealStatus
+	To        DealStatus
+	Actor     string
+	UserID    *int64
+	UserName  string
+	Reason    *string
+	CreatedAt time.Time
+}
+
+// TranslationStatus is where a deal translation is in review. Missing is
+// never stored: it stands for a language with no row or an empty title.
+type TranslationStatus string
+
+const (
+	TranslationMissing  TranslationStatus = "missing"
+	TranslationDraft    TranslationStatus = "draft"
+	TranslationMachine  TranslationStatus = "machine"
+	TranslationReviewed TranslationStatus = "reviewed"
+)
+
+var TranslationStatuses = []TranslationStatus{TranslationMissing, TranslationDraft, TranslationMachine, TranslationReviewed}
+
+func (s TranslationStatus) Valid() bool {
+	for _, x := range TranslationStatuses {
+		if x == s {
+			return true
+		}
+	}
+	return false
 }
 
+// DealTranslation is a deal's text in one language.
 type DealTranslation struct {
 	DealID      int64
 	Lang        string
 	Title       string
 	Description string
+
+	Status       TranslationStatus
+	AuthorID     *int64
+	ReviewedByID *int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ReviewedAt   *time.Time
+}
+
+// TranslationTask is a deal whose translation into Lang needs work, with the
+// deal's own text to translate from.
+type TranslationTask struct {
+	DealID          int64
+	CountryID       int64
+	CountryCode     string
+	DealTitle       string
+	DealDescription string
+	Lang            string
+	Status          TranslationStatus
+	Title           string
+	Description     string
+	AuthorName      string
+	UpdatedAt       *time.Time
+}
+
+// MissingTranslation is deal text in the country's default language, From,
+// that has no translation into To yet.
+type MissingTranslation struct {
+	DealID      int64
+	From, To    string
+	Title       string
+	Description string
+}
+
+// DealSnapshot is a deal's content as stored in a revision. The JSON keys
+// are part of the stored format and must not change with the Go names.
+type DealSnapshot struct {
+	Title        string                `json:"title"`
+	Description  string                `json:"description"`
+	CityID       int64                 `json:"city_id"`
+	CategoryID   int64                 `json:"category_id"`
+	MerchantID   *int64                `json:"merchant_id"`
+	DealTypeID   int64                 `json:"deal_type_id"`
+	StartAt      time.Time             `json:"start_at"`
+	EndAt        time.Time             `json:"end_at"`
+	ImageURL     string                `json:"image_url"`
+	Translations []SnapshotTranslation `json:"translations"`
+}
+
+// SnapshotTranslation is a deal translation as stored in a revision. The
+// review fields are missing from revisions stored before they were added.
+type SnapshotTranslation struct {
+	Lang         string            `json:"lang"`
+	Title        string            `json:"title"`
+	Description  string            `json:"description"`
+	Status       TranslationStatus `json:"status,omitempty"`
+	AuthorID     *int64            `json:"author_id,omitempty"`
+	ReviewedByID *int64            `json:"reviewed_by_id,omitempty"`
+	ReviewedAt   *time.Time        `json:"reviewed_at,omitempty"`
+}
+
+type DealRevision struct {
+	ID              int64
+	DealID          int64
+	Snapshot        DealSnapshot
+	CreatedByUserID int64
+	CreatedByName   string
+	RestoredFromID  *int64
+	CreatedAt       time.Time
+}
+
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
 }
 
 type User struct {
-	ID           int64
-	Email        string
-	PasswordHash string
-	Name         string
-	Role         UserRole
-	CreatedAt    time.Time
+	ID                int64
+	Email             string
+	PasswordHash      string
+	Name              string
+	Role              UserRole
+	CreatedAt         time.Time
+	EmailVerifiedAt   *time.Time
+	TOTPSecret        string
+	TOTPEnabledAt     *time.Time
+	FailedLoginCount  int
+	LastFailedLoginAt *time.Time
+	// LockedUntil is only set while the lock is in force.
+	LockedUntil   *time.Time
+	PreferredLang string
+	CountryIDs    []int64
+	// AllCountries lets the user's permissions apply in every country;
+	// otherwise they only apply in CountryIDs, and with none of those,
+	// nowhere.
+	AllCountries bool
+}
+
+func (u *User) Can(p Permission) bool {
+	return u != nil && u.Role.Can(p)
+}
+
+func (u *User) InCountry(countryID int64) bool {
+	if u.AllCountries {
+		return true
+	}
+	for _, id := range u.CountryIDs {
+		if id == countryID {
+			return true
+		}
+	}
+	return false
+}
+
+// Manages reports whether u's country scope covers all of other's, as
+// acting on another user's account requires. A user with no countries
+// manages no one.
+func (u *User) Manages(other *User) bool {
+	if u.AllCountries {
+		return true
+	}
+	if other.AllCountries || len(u.CountryIDs) == 0 {
+		return false
+	}
+	for _, id := range other.CountryIDs {
+		if !u.InCountry(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanGrant reports whether u may give another user role. Roles that manage
+// users are granted only by users with every country, so a country-scoped
+// admin cannot create admins.
+func (u *User) CanGrant(role UserRole) bool {
+	return u.Can(PermManageUsers) && (u.AllCountries || !role.Can(PermManageUsers))
+}
+
+func (u *User) CanIn(p Permission, countryID int64) bool {
+	return u.Can(p) && u.InCountry(countryID)
+}
+
+type LoginAttempt struct {
+	ID        int64
+	UserID    *int64
+	Email     string
+	IP        string
+	UserAgent string
+	Success   bool
+	Reason    string
+	CreatedAt time.Time
+}
+
+type APIScope string
+
+const (
+	ScopeDealsRead  APIScope = "deals:read"
+	ScopeModeration APIScope = "moderation"
+)
+
+var APIScopes = []APIScope{ScopeDealsRead, ScopeModeration}
+
+type APIToken struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Prefix     string
+	TokenHash  string
+	Scopes     []APIScope
+	RateLimit  int
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type UserTokenPurpose string
+
+const (
+	TokenVerifyEmail   UserTokenPurpose = "verify_email"
+	TokenResetPassword UserTokenPurpose = "reset_password"
+)
+
+type Session struct {
+	ID         int64
+	SID        string
+	UserID     *int64
+	UserAgent  string
+	IP         string
+	ExpiresAt  *time.Time
+	CreatedAt  time.Time
+	LastSeenAt time.Time
 }
 
 type AdminConfig struct {
@@ -103,14 +427,57 @@ type AdminConfig struct {
 	Value []byte
 }
 
+type JobRun struct {
+	ID        int64
+	JobName   string
+	StartedAt time.Time
+	Duration  time.Duration
+	Error     *string
+}
+
 type DealFilter struct {
-	CountryCode  string
-	CitySlug     string
-	CategorySlug string
-	DealTypeID   int64
-	MerchantID   int64
-	Search       string
-	EndingSoon   bool
-	Page         int
-	PageSize     int
+	CountryCode  string   `query:"-"`
+	Langs        []string `query:"-"`
+	CitySlug     string   `query:"city"`
+	CategorySlug string   `query:"category"`
+	DealTypeID   int64    `query:"deal_type"`
+	MerchantID   int64    `query:"merchant"`
+	Search       string   `query:"q"`
+	EndingSoon   bool     `query:"ending_soon"`
+	Page         int      `query:"-"`
+	PageSize     int      `query:"-"`
+	// Keyset lists deals in (end_at, id) order after After instead of by
+	// Page, so concurrent inserts neither skip nor repeat rows.
+	Keyset bool     `query:"-"`
+	After  *DealKey `query:"-"`
+}
+
+// DealKey is a deal's position in the (end_at, id) keyset order.
+type DealKey struct {
+	EndAt time.Time
+	ID    int64
+}
+
+type FacetCount struct {
+	Value string
+	Label string
+	Count int
+}
+
+type DealFacets struct {
+	Cities     []FacetCount
+	Categories []FacetCount
+	DealTypes  []FacetCount
+	Merchants  []FacetCount
+	EndingSoon int
+}
+
+type DealListResult struct {
+	Deals    []Deal
+	Total    int
+	Page     int
+	PageSize int
+	// More reports whether deals follow the last one listed.
+	More   bool
+	Facets DealFacets
 }
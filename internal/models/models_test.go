@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestUserManages(t *testing.T) {
+	all := &User{Role: RoleAdmin, AllCountries: true}
+	tests := []struct {
+		name  string
+		u     *User
+		other *User
+		want  bool
+	}{
+		{"all countries manages all countries", all, &User{AllCountries: true}, true},
+		{"all countries manages scoped", all, &User{CountryIDs: []int64{1, 2}}, true},
+		{"all countries manages unscoped", all, &User{}, true},
+		{"scoped manages subset", &User{CountryIDs: []int64{1, 2}}, &User{CountryIDs: []int64{2}}, true},
+		{"scoped manages same set", &User{CountryIDs: []int64{1, 2}}, &User{CountryIDs: []int64{1, 2}}, true},
+		{"scoped manages unscoped", &User{CountryIDs: []int64{1}}, &User{}, true},
+		{"scoped does not manage overlap", &User{CountryIDs: []int64{1, 2}}, &User{CountryIDs: []int64{2, 3}}, false},
+		{"scoped does not manage disjoint", &User{CountryIDs: []int64{1}}, &User{CountryIDs: []int64{3}}, false},
+		{"scoped does not manage all countries", &User{CountryIDs: []int64{1, 2}}, &User{AllCountries: true}, false},
+		{"unscoped manages no one", &User{}, &User{}, false},
+		{"unscoped does not manage scoped", &User{}, &User{CountryIDs: []int64{1}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.u.Manages(tt.other); got != tt.want {
+			t.Errorf("%s: Manages = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUserCanGrant(t *testing.T) {
+	tests := []struct {
+		name string
+		u    *User
+		role UserRole
+		want bool
+	}{
+		{"admin with all countries grants admin", &User{Role: RoleAdmin, AllCountries: true}, RoleAdmin, true},
+		{"admin with all countries grants moderator", &User{Role: RoleAdmin, AllCountries: true}, RoleModerator, true},
+		{"scoped admin cannot grant admin", &User{Role: RoleAdmin, CountryIDs: []int64{1}}, RoleAdmin, false},
+		{"scoped admin grants moderator", &User{Role: RoleAdmin, CountryIDs: []int64{1}}, RoleModerator, true},
+		{"scoped admin grants submitter", &User{Role: RoleAdmin, CountryIDs: []int64{1}}, RoleSubmitter, true},
+		{"moderator grants nothing", &User{Role: RoleModerator, AllCountries: true}, RoleSubmitter, false},
+	}
+	for _, tt := range tests {
+		if got := tt.u.CanGrant(tt.role); got != tt.want {
+			t.Errorf("%s: CanGrant(%s) = %v, want %v", tt.name, tt.role, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-next-cms/internal/models"
+)
+
+const (
+	facetCity       = "city"
+	facetCategory   = "category"
+	facetDealType   = "deal_type"
+	facetMerchant   = "merchant"
+	facetEndingSoon = "ending_soon"
+)
+
+// dealCond is a filter condition, tagged with the facet dimension it filters
+// on, if any.
+type dealCond struct {
+	Dim string
+	SQL string
+}
+
+type dealConds struct {
+	Conds  []dealCond
+	Args   []any
+	Search searchSQL
+}
+
+func (w *dealConds) add(dim, cond string, arg any) {
+	w.Args = append(w.Args, arg)
+	w.Conds = append(w.Conds, dealCond{dim, fmt.Sprintf(cond, len(w.Args))})
+}
+
+// SQL joins the conditions, leaving out those on dimension skip so facet
+// counts reflect the other active filters. Arguments keep their positions
+// either way, so one statement can combine several dimensions.
+func (w dealConds) SQL(skip string) string {
+	parts := make([]string, 0, len(w.Conds))
+	for _, c := range w.Conds {
+		if skip == "" || c.Dim != skip {
+			parts = append(parts, c.SQL)
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+func (w dealConds) From() string {
+	return `FROM deals d
+	JOIN countries co ON co.id=d.country_id
+	JOIN cities ci ON ci.id=d.city_id
+	JOIN categories ca ON ca.id=d.category_id
+	LEFT JOIN merchants m ON m.id=d.merchant_id
+	JOIN deal_types dt ON dt.id=d.deal_type_id
+	` + w.Search.Join
+}
+
+// facetDims are the counted dimensions in result order: the value counted
+// and the master data entity, joined as alias, that labels it.
+var facetDims = []struct {
+	dim, value string
+	entity     models.MasterEntity
+	alias      string
+}{
+	{facetCity, "ci.slug", models.EntityCity, "ci"},
+	{facetCategory, "ca.slug", models.EntityCategory, "ca"},
+	{facetDealType, "d.deal_type_id::text", models.EntityDealType, "dt"},
+	{facetMerchant, "d.merchant_id::text", models.EntityMerchant, "m"},
+}
+
+// dealFacets counts the deals matching w per facet value in one statement,
+// each dimension ignoring its own filter. Labels are the localized names of
+// the joined rows; grouping by alias.id lets the label use them.
+func (r *Repository) dealFacets(ctx context.Context, w dealConds, langs []string, out *models.DealFacets) error {
+	args := append(w.Args[:len(w.Args):len(w.Args)], langs)
+	parts := make([]string, 0, len(facetDims)+1)
+	for i, fd := range facetDims {
+		parts = append(parts, fmt.Sprintf(`SELECT %d, %s, %s, COUNT(*) %s WHERE %s AND %s IS NOT NULL GROUP BY 2, %s.id`,
+			i, fd.value, localName(fd.entity, fd.alias, len(args)), w.From(), w.SQL(fd.dim), fd.value, fd.alias))
+	}
+	parts = append(parts, fmt.Sprintf(`SELECT %d, '', '', COUNT(*) %s WHERE %s AND d.end_at <= NOW() + INTERVAL '7 days'`, len(facetDims), w.From(), w.SQL(facetEndingSoon)))
+	rows, err := r.DB.Query(ctx, strings.Join(parts, "\nUNION ALL\n")+"\nORDER BY 1, 3", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	lists := []*[]models.FacetCount{&out.Cities, &out.Categories, &out.DealTypes, &out.Merchants}
+	for rows.Next() {
+		var i int
+		var fc models.FacetCount
+		if err := rows.Scan(&i, &fc.Value, &fc.Label, &fc.Count); err != nil {
+			return err
+		}
+		if i == len(facetDims) {
+			out.EndingSoon = fc.Count
+			continue
+		}
+		*lists[i] = append(*lists[i], fc)
+	}
+	return rows.Err()
+}
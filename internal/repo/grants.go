@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+)
+
+// SetUserCountries replaces userID's country grants with countryIDs, or with
+// every country when all is set.
+func (r *Repository) SetUserCountries(ctx context.Context, userID int64, all bool, countryIDs []int64) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `UPDATE users SET all_countries=$2 WHERE id=$1`, userID, all); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_countries WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO user_countries (user_id,country_id) SELECT $1, unnest($2::bigint[]) ON CONFLICT DO NOTHING`, userID, countryIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"go-next-cms/internal/models"
+)
+
+func (r *Repository) WithAdvisoryLock(ctx context.Context, key string, fn func(ctx context.Context) error) (bool, error) {
+	conn, err := r.DB.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+	var ok bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, key).Scan(&ok); err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, key)
+	return true, fn(ctx)
+}
+
+func (r *Repository) RecordJobRun(ctx context.Context, name string, startedAt time.Time, d time.Duration, runErr error) error {
+	var msg *string
+	if runErr != nil {
+		s := runErr.Error()
+		msg = &s
+	}
+	_, err := r.DB.Exec(ctx, `INSERT INTO job_runs (job_name,started_at,duration_ms,error) VALUES ($1,$2,$3,$4)`, name, startedAt, d.Milliseconds(), msg)
+	return err
+}
+
+func (r *Repository) LatestJobRuns(ctx context.Context) ([]models.JobRun, error) {
+	rows, err := r.DB.Query(ctx, `SELECT DISTINCT ON (job_name) id,job_name,started_at,duration_ms,error FROM job_runs ORDER BY job_name, started_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.JobRun
+	for rows.Next() {
+		var j models.JobRun
+		var ms int64
+		if err := rows.Scan(&j.ID, &j.JobName, &j.StartedAt, &ms, &j.Error); err != nil {
+			return nil, err
+		}
+		j.Duration = time.Duration(ms) * time.Millisecond
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) DeleteJobRunsBefore(ctx context.Context, t time.Time) error {
+	_, err := r.DB.Exec(ctx, `DELETE FROM job_runs WHERE started_at < $1`, t)
+	return err
+}
@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-next-cms/internal/models"
+)
+
+func (r *Repository) RecordLoginAttempt(ctx context.Context, a *models.LoginAttempt) error {
+	return r.DB.QueryRow(ctx, `INSERT INTO login_attempts (user_id,email,ip,user_agent,success,reason) VALUES ($1,$2,$3,$4,$5,$6) RETURNING id,created_at`,
+		a.UserID, a.Email, a.IP, a.UserAgent, a.Success, a.Reason).Scan(&a.ID, &a.CreatedAt)
+}
+
+// LoginThrottle is a lockout and progressive delay policy. The repository
+// applies it against the database clock, the one the timestamps are kept in.
+type LoginThrottle struct {
+	// LockAfter failures lock the account, or block the IP, for LockFor.
+	LockAfter int
+	LockFor   time.Duration
+	// DelayFree failures pass without delay; after that each waits one
+	// second, doubling per failure up to MaxDelay, from the last failure.
+	DelayFree int
+	MaxDelay  time.Duration
+}
+
+// LoginGate is the outcome of a throttled login step.
+type LoginGate struct {
+	Passed     bool
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+// delaySQL is the delay interval after count failures under the DelayFree
+// and MaxDelay seconds bound to the parameters at free and max.
+func delaySQL(count string, free, max int) string {
+	return fmt.Sprintf("make_interval(secs => CASE WHEN %[1]s < $%[2]d THEN 0 ELSE LEAST($%[3]d::float8, power(2::float8, %[1]s - $%[2]d)) END)", count, free, max)
+}
+
+func fromSeconds(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
+
+// IPLoginGate checks the failed logins from ip within p.LockFor: the IP is
+// blocked at p.LockAfter of them and delayed progressively before that.
+// Refused attempts do not count, so waiting out a delay is enough.
+func (r *Repository) IPLoginGate(ctx context.Context, ip string, p LoginThrottle) (LoginGate, error) {
+	var n int
+	var wait float64
+	err := r.DB.QueryRow(ctx, `SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM MAX(created_at) + `+delaySQL("COUNT(*)", 3, 4)+` - NOW()), 0)::float8
+	FROM login_attempts WHERE ip=$1 AND success=false AND reason NOT IN ('ip_blocked','ip_throttled','locked','throttled')
+	AND created_at > NOW() - make_interval(secs => $2)`, ip, p.LockFor.Seconds(), p.DelayFree, p.MaxDelay.Seconds()).Scan(&n, &wait)
+	switch {
+	case err != nil:
+		return LoginGate{}, err
+	case n >= p.LockAfter:
+		return LoginGate{RetryAfter: p.LockFor, Locked: true}, nil
+	case wait > 0:
+		return LoginGate{RetryAfter: fromSeconds(wait)}, nil
+	}
+	return LoginGate{Passed: true}, nil
+}
+
+// TryLogin runs check, a password or second factor check, unless the account
+// is locked or inside its progressive delay. A failed check counts against
+// the account and locks it at p.LockAfter failures, counted afresh once an
+// earlier lock has expired; a passed one leaves the counters to
+// ResetLoginFailures. Attempts on one account are serialized by a
+// transaction-scoped advisory lock, so concurrent guesses cannot all pass the
+// same gate; check may use the pool but must not wait on the lock.
+func (r *Repository) TryLogin(ctx context.Context, userID int64, p LoginThrottle, check func() (bool, error)) (LoginGate, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return LoginGate{}, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('login'), hashtext($1::bigint::text))`, userID); err != nil {
+		return LoginGate{}, err
+	}
+	var lock, delay float64
+	err = tx.QueryRow(ctx, `SELECT COALESCE(EXTRACT(EPOCH FROM locked_until - NOW()), 0)::float8,
+	COALESCE(EXTRACT(EPOCH FROM last_failed_login_at + `+delaySQL("failed_login_count", 2, 3)+` - NOW()), 0)::float8
+	FROM users WHERE id=$1`, userID, p.DelayFree, p.MaxDelay.Seconds()).Scan(&lock, &delay)
+	switch {
+	case err != nil:
+		return LoginGate{}, err
+	case lock > 0:
+		return LoginGate{RetryAfter: fromSeconds(lock), Locked: true}, nil
+	case delay > 0:
+		return LoginGate{RetryAfter: fromSeconds(delay)}, nil
+	}
+	ok, err := check()
+	if err != nil {
+		return LoginGate{}, err
+	}
+	if !ok {
+		// A lock that has run out starts a fresh count, so one more failure
+		// does not lock the account again straight away.
+		if _, err := tx.Exec(ctx, `UPDATE users SET failed_login_count=0,locked_until=NULL WHERE id=$1 AND locked_until <= NOW()`, userID); err != nil {
+			return LoginGate{}, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE users SET failed_login_count=failed_login_count+1,last_failed_login_at=NOW(),
+		locked_until=CASE WHEN failed_login_count+1 >= $2 THEN NOW() + make_interval(secs => $3) ELSE locked_until END WHERE id=$1`, userID, p.LockAfter, p.LockFor.Seconds()); err != nil {
+			return LoginGate{}, err
+		}
+	}
+	return LoginGate{Passed: ok}, tx.Commit(ctx)
+}
+
+func (r *Repository) ResetLoginFailures(ctx context.Context, userID int64) error {
+	_, err := r.DB.Exec(ctx, `UPDATE users SET failed_login_count=0,last_failed_login_at=NULL,locked_until=NULL WHERE id=$1`, userID)
+	return err
+}
+
+func (r *Repository) LoginAttempts(ctx context.Context, limit int) ([]models.LoginAttempt, error) {
+	rows, err := r.DB.Query(ctx, `SELECT id,user_id,email,ip,user_agent,success,reason,created_at FROM login_attempts ORDER BY created_at DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.LoginAttempt
+	for rows.Next() {
+		var a models.LoginAttempt
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Email, &a.IP, &a.UserAgent, &a.Success, &a.Reason, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) DeleteLoginAttemptsBefore(ctx context.Context, t time.Time) error {
+	_, err := r.DB.Exec(ctx, `DELETE FROM login_attempts WHERE created_at < $1`, t)
+	return err
+}
@@ -0,0 +1,39 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTryLoginCountsAfreshAfterLock(t *testing.T) {
+	r := testRepo(t)
+	ctx := context.Background()
+	u := testUser(t, r)
+	p := LoginThrottle{LockAfter: 3, LockFor: time.Minute, DelayFree: 10, MaxDelay: time.Second}
+	fail := func() (bool, error) { return false, nil }
+
+	for i := 0; i < p.LockAfter; i++ {
+		if gate, err := r.TryLogin(ctx, u.ID, p, fail); err != nil || gate.Locked {
+			t.Fatalf("failure %d: gate = %+v, err = %v", i+1, gate, err)
+		}
+	}
+	if gate, err := r.TryLogin(ctx, u.ID, p, fail); err != nil || !gate.Locked {
+		t.Fatalf("after %d failures: gate = %+v, err = %v; want locked", p.LockAfter, gate, err)
+	}
+
+	// Let the lock run out on the database clock.
+	if _, err := r.DB.Exec(ctx, `UPDATE users SET locked_until=NOW() - INTERVAL '1 second' WHERE id=$1`, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if gate, err := r.TryLogin(ctx, u.ID, p, fail); err != nil || gate.Locked {
+		t.Fatalf("first failure after the lock: gate = %+v, err = %v", gate, err)
+	}
+	got, err := r.UserByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FailedLoginCount != 1 || got.LockedUntil != nil {
+		t.Errorf("after an expired lock: count = %d, locked until %v; want 1, none", got.FailedLoginCount, got.LockedUntil)
+	}
+}
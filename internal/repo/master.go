@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-next-cms/internal/models"
+)
+
+// localName is the SQL for the name of the entity row alias in the first
+// language of the chain bound to $arg that has one, falling back to the row's
+// own name.
+func localName(entity models.MasterEntity, alias string, arg int) string {
+	return fmt.Sprintf("COALESCE((SELECT mt.name FROM master_translations mt WHERE mt.entity='%s' AND mt.entity_id=%s.id AND mt.lang=ANY($%d::text[]) ORDER BY array_position($%d::text[], mt.lang) LIMIT 1), %s.name)", entity, alias, arg, arg, alias)
+}
+
+// localNameJoin is a lateral join, as alias_name, of the name of the entity
+// row alias in the first language of the chain bound to $arg that has one.
+// Columns read COALESCE(alias_name.name, alias.name).
+func localNameJoin(entity models.MasterEntity, alias string, arg int) string {
+	return fmt.Sprintf("LEFT JOIN LATERAL (SELECT mt.name FROM master_translations mt WHERE mt.entity='%s' AND mt.entity_id=%s.id AND mt.lang=ANY($%d::text[]) ORDER BY array_position($%d::text[], mt.lang) LIMIT 1) %s_name ON true", entity, alias, arg, arg, alias)
+}
+
+// dealTextJoins are the lateral joins dealColumns reads from: tr is the
+// deal_translations row of deal d in the first language of the chain bound to
+// $arg with a title, and a reviewed status when reviewedOnly is set; the
+// master data names follow localNameJoin.
+func dealTextJoins(arg int, reviewedOnly bool) string {
+	reviewed := ""
+	if reviewedOnly {
+		reviewed = " AND t.status='reviewed'"
+	}
+	joins := []string{fmt.Sprintf("LEFT JOIN LATERAL (SELECT t.title, t.description FROM deal_translations t WHERE t.deal_id=d.id AND t.title<>''%s AND t.lang=ANY($%d::text[]) ORDER BY array_position($%d::text[], t.lang) LIMIT 1) tr ON true", reviewed, arg, arg)}
+	for _, j := range dealNameJoins {
+		joins = append(joins, localNameJoin(j.entity, j.alias, arg))
+	}
+	return strings.Join(joins, "\n\t")
+}
+
+// dealNameJoins are the master data rows joined to deals whose names are
+// translated.
+var dealNameJoins = []struct {
+	entity models.MasterEntity
+	alias  string
+}{
+	{models.EntityCity, "ci"},
+	{models.EntityCategory, "ca"},
+	{models.EntityMerchant, "m"},
+	{models.EntityDealType, "dt"},
+}
+
+var masterTables = map[models.MasterEntity]string{
+	models.EntityCity:     "cities",
+	models.EntityCategory: "categories",
+	models.EntityDealType: "deal_types",
+	models.EntityMerchant: "merchants",
+}
+
+// MasterExists reports whether entity has a row with id.
+func (r *Repository) MasterExists(ctx context.Context, entity models.MasterEntity, id int64) (bool, error) {
+	table, ok := masterTables[entity]
+	if !ok {
+		return false, fmt.Errorf("unknown master data entity %q", entity)
+	}
+	var exists bool
+	err := r.DB.QueryRow(ctx, fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE id=$1)`, table), id).Scan(&exists)
+	return exists, err
+}
+
+// MasterTranslations returns the translated names of entity rows by ID and
+// language.
+func (r *Repository) MasterTranslations(ctx context.Context, entity models.MasterEntity) (map[int64]map[string]string, error) {
+	rows, err := r.DB.Query(ctx, `SELECT entity_id,lang,name FROM master_translations WHERE entity=$1`, entity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]map[string]string{}
+	for rows.Next() {
+		var id int64
+		var lang, name string
+		if err := rows.Scan(&id, &lang, &name); err != nil {
+			return nil, err
+		}
+		if out[id] == nil {
+			out[id] = map[string]string{}
+		}
+		out[id][lang] = name
+	}
+	return out, rows.Err()
+}
+
+// SetMasterTranslations stores the names of one entity row by language; an
+// empty name removes that language.
+func (r *Repository) SetMasterTranslations(ctx context.Context, entity models.MasterEntity, id int64, names map[string]string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	for lang, name := range names {
+		if name == "" {
+			if _, err := tx.Exec(ctx, `DELETE FROM master_translations WHERE entity=$1 AND entity_id=$2 AND lang=$3`, entity, id, lang); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO master_translations(entity,entity_id,lang,name) VALUES($1,$2,$3,$4)
+		ON CONFLICT (entity,entity_id,lang) DO UPDATE SET name=EXCLUDED.name`, entity, id, lang, name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
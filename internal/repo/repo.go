@@ -12,12 +12,18 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-type Repository struct{ DB *pgxpool.Pool }
+type Repository struct {
+	DB *pgxpool.Pool
+
+	// HideUnreviewedTranslations makes public deal queries skip translations
+	// no translator or moderator has reviewed.
+	HideUnreviewedTranslations bool
+}
 
 func New(db *pgxpool.Pool) *Repository { return &Repository{DB: db} }
 
 func (r *Repository) Countries(ctx context.Context) ([]models.Country, error) {
-	rows, err := r.DB.Query(ctx, `SELECT id, code, name, default_language FROM countries ORDER BY name`)
+	rows, err := r.DB.Query(ctx, `SELECT id, code, name, default_language, languages, fallbacks FROM countries ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
@@ -25,7 +31,7 @@ func (r *Repository) Countries(ctx context.Context) ([]models.Country, error) {
 	out := []models.Country{}
 	for rows.Next() {
 		var c models.Country
-		if err := rows.Scan(&c.ID, &c.Code, &c.Name, &c.DefaultLanguage); err != nil {
+		if err := rows.Scan(&c.ID, &c.Code, &c.Name, &c.DefaultLanguage, &c.Languages, &c.Fallbacks); err != nil {
 			return nil, err
 		}
 		out = append(out, c)
@@ -33,17 +39,31 @@ func (r *Repository) Countries(ctx context.Context) ([]models.Country, error) {
 	return out, rows.Err()
 }
 
-func (r *Repository) CountryByCode(ctx context.Context, code string) (*models.Country, error) {
+func (r *Repository) CountryByID(ctx context.Context, id int64) (*models.Country, error) {
 	var c models.Country
-	err := r.DB.QueryRow(ctx, `SELECT id, code, name, default_language FROM countries WHERE code=$1`, strings.ToUpper(code)).Scan(&c.ID, &c.Code, &c.Name, &c.DefaultLanguage)
+	err := r.DB.QueryRow(ctx, `SELECT id, code, name, default_language, languages, fallbacks FROM countries WHERE id=$1`, id).Scan(&c.ID, &c.Code, &c.Name, &c.DefaultLanguage, &c.Languages, &c.Fallbacks)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *Repository) SetCountryLanguages(ctx context.Context, id int64, langs []string, fallbacks string) error {
+	_, err := r.DB.Exec(ctx, `UPDATE countries SET languages=$2, fallbacks=$3 WHERE id=$1`, id, langs, fallbacks)
+	return err
+}
+
+func (r *Repository) CityByID(ctx context.Context, id int64) (*models.City, error) {
+	var c models.City
+	err := r.DB.QueryRow(ctx, `SELECT id,country_id,name,slug FROM cities WHERE id=$1`, id).Scan(&c.ID, &c.CountryID, &c.Name, &c.Slug)
 	if err != nil {
 		return nil, err
 	}
 	return &c, nil
 }
 
-func (r *Repository) CitiesByCountry(ctx context.Context, countryID int64) ([]models.City, error) {
-	rows, err := r.DB.Query(ctx, `SELECT id,country_id,name,slug FROM cities WHERE country_id=$1 ORDER BY name`, countryID)
+func (r *Repository) CitiesByCountry(ctx context.Context, countryID int64, langs []string) ([]models.City, error) {
+	rows, err := r.DB.Query(ctx, fmt.Sprintf(`SELECT ci.id,ci.country_id,%s,ci.slug FROM cities ci WHERE ci.country_id=$1 ORDER BY 3`, localName(models.EntityCity, "ci", 2)), countryID, langs)
 	if err != nil {
 		return nil, err
 	}
@@ -59,8 +79,8 @@ func (r *Repository) CitiesByCountry(ctx context.Context, countryID int64) ([]mo
 	return out, rows.Err()
 }
 
-func (r *Repository) Categories(ctx context.Context) ([]models.Category, error) {
-	rows, err := r.DB.Query(ctx, `SELECT id,name,slug FROM categories ORDER BY name`)
+func (r *Repository) Categories(ctx context.Context, langs []string) ([]models.Category, error) {
+	rows, err := r.DB.Query(ctx, fmt.Sprintf(`SELECT ca.id,%s,ca.slug FROM categories ca ORDER BY 2`, localName(models.EntityCategory, "ca", 1)), langs)
 	if err != nil {
 		return nil, err
 	}
@@ -76,8 +96,8 @@ func (r *Repository) Categories(ctx context.Context) ([]models.Category, error)
 	return out, rows.Err()
 }
 
-func (r *Repository) Merchants(ctx context.Context) ([]models.Merchant, error) {
-	rows, err := r.DB.Query(ctx, `SELECT id,name,slug,logo_url,contact,verified FROM merchants ORDER BY name`)
+func (r *Repository) Merchants(ctx context.Context, langs []string) ([]models.Merchant, error) {
+	rows, err := r.DB.Query(ctx, fmt.Sprintf(`SELECT m.id,%s,m.slug,m.logo_url,m.contact,m.verified FROM merchants m ORDER BY 2`, localName(models.EntityMerchant, "m", 1)), langs)
 	if err != nil {
 		return nil, err
 	}
@@ -93,8 +113,8 @@ func (r *Repository) Merchants(ctx context.Context) ([]models.Merchant, error) {
 	return out, rows.Err()
 }
 
-func (r *Repository) DealTypes(ctx context.Context) ([]models.DealType, error) {
-	rows, err := r.DB.Query(ctx, `SELECT id,code,name FROM deal_types ORDER BY name`)
+func (r *Repository) DealTypes(ctx context.Context, langs []string) ([]models.DealType, error) {
+	rows, err := r.DB.Query(ctx, fmt.Sprintf(`SELECT dt.id,dt.code,%s FROM deal_types dt ORDER BY 3`, localName(models.EntityDealType, "dt", 1)), langs)
 	if err != nil {
 		return nil, err
 	}
@@ -110,73 +130,105 @@ func (r *Repository) DealTypes(ctx context.Context) ([]models.DealType, error) {
 	return out, rows.Err()
 }
 
-func (r *Repository) ListDeals(ctx context.Context, f models.DealFilter) ([]models.Deal, error) {
+func (r *Repository) ListDeals(ctx context.Context, f models.DealFilter) (*models.DealListResult, error) {
 	if f.Page < 1 {
 		f.Page = 1
 	}
 	if f.PageSize < 1 {
 		f.PageSize = 10
 	}
-	where := []string{"d.status='published'", "d.end_at > NOW()", "co.code=$1"}
-	args := []any{strings.ToUpper(f.CountryCode)}
-	idx := 2
+	w := dealWhere(f)
+	args := append(w.Args[:len(w.Args):len(w.Args)], f.Langs)
+	langs := len(args)
+	where, offset := w.SQL(""), (f.Page-1)*f.PageSize
+	order := w.Search.Rank + " DESC, d.featured DESC, d.end_at ASC"
+	if f.Keyset {
+		// The keyset bound stays out of w: the total and facets cover every
+		// match, not only those after the cursor.
+		order, offset = "d.end_at ASC, d.id ASC", 0
+		if f.After != nil {
+			args = append(args, f.After.EndAt, f.After.ID)
+			where += fmt.Sprintf(" AND (d.end_at, d.id) > ($%d, $%d)", len(args)-1, len(args))
+		}
+	}
+	q := fmt.Sprintf(`SELECT %s,%s,COUNT(*) OVER()
+	%s
+	%s
+	WHERE %s
+	ORDER BY %s
+	LIMIT %d OFFSET %d`, dealColumns, w.Search.Snippet, w.From(), r.dealJoins(langs), where, order, f.PageSize, offset)
+
+	rows, err := r.DB.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := &models.DealListResult{Page: f.Page, PageSize: f.PageSize}
+	res.Deals, err = scanDealsWith(rows, func(d *models.Deal) []any { return []any{&d.Snippet, &res.Total} })
+	if err != nil {
+		return nil, err
+	}
+	res.More = res.Total > offset+len(res.Deals)
+	// A page past the end has no rows to carry the total, and after a
+	// keyset bound the rows only count the deals that follow it.
+	if len(res.Deals) == 0 && f.Page > 1 || f.After != nil {
+		if err := r.DB.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) %s WHERE %s`, w.From(), w.SQL("")), w.Args...).Scan(&res.Total); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.dealFacets(ctx, w, f.Langs, &res.Facets); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// dealWhere builds the filter conditions for f.
+func dealWhere(f models.DealFilter) dealConds {
+	w := dealConds{Conds: []dealCond{{SQL: "d.status='published'"}, {SQL: "d.end_at > NOW()"}, {SQL: "co.code=$1"}}, Args: []any{strings.ToUpper(f.CountryCode)}, Search: noSearch}
 	if f.CitySlug != "" {
-		where = append(where, fmt.Sprintf("ci.slug=$%d", idx))
-		args = append(args, f.CitySlug)
-		idx++
+		w.add(facetCity, "ci.slug=$%d", f.CitySlug)
 	}
 	if f.CategorySlug != "" {
-		where = append(where, fmt.Sprintf("ca.slug=$%d", idx))
-		args = append(args, f.CategorySlug)
-		idx++
+		w.add(facetCategory, "ca.slug=$%d", f.CategorySlug)
 	}
 	if f.DealTypeID > 0 {
-		where = append(where, fmt.Sprintf("d.deal_type_id=$%d", idx))
-		args = append(args, f.DealTypeID)
-		idx++
+		w.add(facetDealType, "d.deal_type_id=$%d", f.DealTypeID)
 	}
 	if f.MerchantID > 0 {
-		where = append(where, fmt.Sprintf("d.merchant_id=$%d", idx))
-		args = append(args, f.MerchantID)
-		idx++
+		w.add(facetMerchant, "d.merchant_id=$%d", f.MerchantID)
 	}
 	if f.Search != "" {
-		where = append(where, fmt.Sprintf("(LOWER(d.title) LIKE $%d OR LOWER(d.description) LIKE $%d)", idx, idx))
-		args = append(args, "%"+strings.ToLower(f.Search)+"%")
-		idx++
+		w.Search = searchClause(len(w.Args) + 1)
+		w.Conds = append(w.Conds, dealCond{SQL: w.Search.Where})
+		w.Args = append(w.Args, f.Search)
 	}
 	if f.EndingSoon {
-		where = append(where, "d.end_at <= NOW() + INTERVAL '7 days'")
+		w.Conds = append(w.Conds, dealCond{facetEndingSoon, "d.end_at <= NOW() + INTERVAL '7 days'"})
 	}
+	return w
+}
 
-	q := fmt.Sprintf(`SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code, d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at
-	FROM deals d
-	JOIN countries co ON co.id=d.country_id
-	JOIN cities ci ON ci.id=d.city_id
-	JOIN categories ca ON ca.id=d.category_id
-	LEFT JOIN merchants m ON m.id=d.merchant_id
-	JOIN deal_types dt ON dt.id=d.deal_type_id
-	WHERE %s
-	ORDER BY d.featured DESC, d.end_at ASC
-	LIMIT %d OFFSET %d`, strings.Join(where, " AND "), f.PageSize, (f.Page-1)*f.PageSize)
+// dealColumns are the columns scanDeals reads, with the title, description
+// and master data names in the best language of the chain, as joined by
+// dealJoins.
+const dealColumns = "d.id,COALESCE(tr.title,d.title),d.slug,COALESCE(tr.description,d.description),d.country_id,co.code,d.city_id,COALESCE(ci_name.name,ci.name),d.category_id,COALESCE(ca_name.name,ca.name),ca.slug,d.merchant_id,COALESCE(m_name.name,m.name),d.deal_type_id,COALESCE(dt_name.name,dt.name),d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at,d.approved_at,d.published_at"
 
-	rows, err := r.DB.Query(ctx, q, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	return scanDeals(rows)
+// dealJoins are the joins dealColumns needs beyond the master data tables,
+// for the chain bound to $langs.
+func (r *Repository) dealJoins(langs int) string {
+	return dealTextJoins(langs, r.HideUnreviewedTranslations)
 }
 
-func (r *Repository) FeaturedDeals(ctx context.Context, countryCode string, limit int) ([]models.Deal, error) {
-	rows, err := r.DB.Query(ctx, `SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code,d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at
+func (r *Repository) FeaturedDeals(ctx context.Context, countryCode string, langs []string, limit int) ([]models.Deal, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+dealColumns+`
 	FROM deals d
 	JOIN countries co ON co.id=d.country_id
 	JOIN cities ci ON ci.id=d.city_id
 	JOIN categories ca ON ca.id=d.category_id
 	LEFT JOIN merchants m ON m.id=d.merchant_id
 	JOIN deal_types dt ON dt.id=d.deal_type_id
-	WHERE d.status='published' AND d.end_at>NOW() AND d.featured=true AND co.code=$1 ORDER BY d.created_at DESC LIMIT $2`, strings.ToUpper(countryCode), limit)
+	`+r.dealJoins(3)+`
+	WHERE d.status='published' AND d.end_at>NOW() AND d.featured=true AND co.code=$1 ORDER BY d.created_at DESC LIMIT $2`, strings.ToUpper(countryCode), limit, langs)
 	if err != nil {
 		return nil, err
 	}
@@ -184,15 +236,16 @@ func (r *Repository) FeaturedDeals(ctx context.Context, countryCode string, limi
 	return scanDeals(rows)
 }
 
-func (r *Repository) EndingSoonDeals(ctx context.Context, countryCode string, limit int) ([]models.Deal, error) {
-	rows, err := r.DB.Query(ctx, `SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code,d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at
+func (r *Repository) EndingSoonDeals(ctx context.Context, countryCode string, langs []string, limit int) ([]models.Deal, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+dealColumns+`
 	FROM deals d
 	JOIN countries co ON co.id=d.country_id
 	JOIN cities ci ON ci.id=d.city_id
 	JOIN categories ca ON ca.id=d.category_id
 	LEFT JOIN merchants m ON m.id=d.merchant_id
 	JOIN deal_types dt ON dt.id=d.deal_type_id
-	WHERE d.status='published' AND d.end_at>NOW() AND d.end_at<=NOW()+INTERVAL '7 days' AND co.code=$1 ORDER BY d.end_at ASC LIMIT $2`, strings.ToUpper(countryCode), limit)
+	`+r.dealJoins(3)+`
+	WHERE d.status='published' AND d.end_at>NOW() AND d.end_at<=NOW()+INTERVAL '7 days' AND co.code=$1 ORDER BY d.end_at ASC LIMIT $2`, strings.ToUpper(countryCode), limit, langs)
 	if err != nil {
 		return nil, err
 	}
@@ -200,15 +253,16 @@ func (r *Repository) EndingSoonDeals(ctx context.Context, countryCode string, li
 	return scanDeals(rows)
 }
 
-func (r *Repository) DealBySlug(ctx context.Context, countryCode, slug string) (*models.Deal, error) {
-	rows, err := r.DB.Query(ctx, `SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code,d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at
+func (r *Repository) DealBySlug(ctx context.Context, countryCode, slug string, langs []string) (*models.Deal, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+dealColumns+`
 	FROM deals d
 	JOIN countries co ON co.id=d.country_id
 	JOIN cities ci ON ci.id=d.city_id
 	JOIN categories ca ON ca.id=d.category_id
 	LEFT JOIN merchants m ON m.id=d.merchant_id
 	JOIN deal_types dt ON dt.id=d.deal_type_id
-	WHERE d.slug=$1 AND co.code=$2 LIMIT 1`, slug, strings.ToUpper(countryCode))
+	`+r.dealJoins(3)+`
+	WHERE d.slug=$1 AND co.code=$2 LIMIT 1`, slug, strings.ToUpper(countryCode), langs)
 	if err != nil {
 		return nil, err
 	}
@@ -223,11 +277,19 @@ func (r *Repository) DealBySlug(ctx context.Context, countryCode, slug string) (
 	return &ds[0], nil
 }
 
-func scanDeals(rows pgx.Rows) ([]models.Deal, error) {
+func scanDeals(rows pgx.Rows) ([]models.Deal, error) { return scanDealsWith(rows, nil) }
+
+// scanDealsWith scans the standard deal columns followed by any extra
+// columns whose destinations are returned by extra.
+func scanDealsWith(rows pgx.Rows, extra func(d *models.Deal) []any) ([]models.Deal, error) {
 	var out []models.Deal
 	for rows.Next() {
 		var d models.Deal
-		if err := rows.Scan(&d.ID, &d.Title, &d.Slug, &d.Description, &d.CountryID, &d.CountryCode, &d.CityID, &d.CityName, &d.CategoryID, &d.CategoryName, &d.CategorySlug, &d.MerchantID, &d.MerchantName, &d.DealTypeID, &d.DealTypeName, &d.StartAt, &d.EndAt, &d.Featured, &d.ImageURL, &d.Status, &d.CreatedByUserID, &d.RejectionReason, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		dest := []any{&d.ID, &d.Title, &d.Slug, &d.Description, &d.CountryID, &d.CountryCode, &d.CityID, &d.CityName, &d.CategoryID, &d.CategoryName, &d.CategorySlug, &d.MerchantID, &d.MerchantName, &d.DealTypeID, &d.DealTypeName, &d.StartAt, &d.EndAt, &d.Featured, &d.ImageURL, &d.Status, &d.CreatedByUserID, &d.RejectionReason, &d.CreatedAt, &d.UpdatedAt, &d.ApprovedAt, &d.PublishedAt}
+		if extra != nil {
+			dest = append(dest, extra(&d)...)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 		out = append(out, d)
@@ -235,13 +297,21 @@ func scanDeals(rows pgx.Rows) ([]models.Deal, error) {
 	return out, rows.Err()
 }
 
-func (r *Repository) DealTranslation(ctx context.Context, dealID int64, lang string) (*models.DealTranslation, error) {
-	var t models.DealTranslation
-	err := r.DB.QueryRow(ctx, `SELECT deal_id,lang,title,description FROM deal_translations WHERE deal_id=$1 AND lang=$2`, dealID, lang).Scan(&t.DealID, &t.Lang, &t.Title, &t.Description)
+func (r *Repository) DealTranslations(ctx context.Context, dealID int64) ([]models.DealTranslation, error) {
+	rows, err := r.DB.Query(ctx, `SELECT deal_id,lang,title,description,status,author_user_id,reviewed_by_user_id,created_at,updated_at,reviewed_at FROM deal_translations WHERE deal_id=$1 ORDER BY lang`, dealID)
 	if err != nil {
 		return nil, err
 	}
-	return &t, nil
+	defer rows.Close()
+	var out []models.DealTranslation
+	for rows.Next() {
+		var t models.DealTranslation
+		if err := rows.Scan(&t.DealID, &t.Lang, &t.Title, &t.Description, &t.Status, &t.AuthorID, &t.ReviewedByID, &t.CreatedAt, &t.UpdatedAt, &t.ReviewedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
 }
 
 func (r *Repository) CreateUser(ctx context.Context, u *models.User) error {
@@ -250,7 +320,7 @@ func (r *Repository) CreateUser(ctx context.Context, u *models.User) error {
 
 func (r *Repository) UserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var u models.User
-	err := r.DB.QueryRow(ctx, `SELECT id,email,password_hash,name,role,created_at FROM users WHERE email=$1`, strings.ToLower(email)).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt)
+	err := r.DB.QueryRow(ctx, `SELECT id,email,password_hash,name,role,created_at,email_verified_at,COALESCE(totp_secret,''),totp_enabled_at,failed_login_count,last_failed_login_at,CASE WHEN locked_until > NOW() THEN locked_until END,preferred_lang,ARRAY(SELECT country_id FROM user_countries uc WHERE uc.user_id=users.id ORDER BY country_id),all_countries FROM users WHERE email=$1`, strings.ToLower(email)).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt, &u.EmailVerifiedAt, &u.TOTPSecret, &u.TOTPEnabledAt, &u.FailedLoginCount, &u.LastFailedLoginAt, &u.LockedUntil, &u.PreferredLang, &u.CountryIDs, &u.AllCountries)
 	if err != nil {
 		return nil, err
 	}
@@ -259,7 +329,7 @@ func (r *Repository) UserByEmail(ctx context.Context, email string) (*models.Use
 
 func (r *Repository) UserByID(ctx context.Context, id int64) (*models.User, error) {
 	var u models.User
-	err := r.DB.QueryRow(ctx, `SELECT id,email,password_hash,name,role,created_at FROM users WHERE id=$1`, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt)
+	err := r.DB.QueryRow(ctx, `SELECT id,email,password_hash,name,role,created_at,email_verified_at,COALESCE(totp_secret,''),totp_enabled_at,failed_login_count,last_failed_login_at,CASE WHEN locked_until > NOW() THEN locked_until END,preferred_lang,ARRAY(SELECT country_id FROM user_countries uc WHERE uc.user_id=users.id ORDER BY country_id),all_countries FROM users WHERE id=$1`, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt, &u.EmailVerifiedAt, &u.TOTPSecret, &u.TOTPEnabledAt, &u.FailedLoginCount, &u.LastFailedLoginAt, &u.LockedUntil, &u.PreferredLang, &u.CountryIDs, &u.AllCountries)
 	if err != nil {
 		return nil, err
 	}
@@ -278,16 +348,19 @@ func (r *Repository) CreateDeal(ctx context.Context, d *models.Deal, translation
 		return err
 	}
 	for _, t := range translations {
-		_, err = tx.Exec(ctx, `INSERT INTO deal_translations (deal_id,lang,title,description) VALUES ($1,$2,$3,$4)`, d.ID, t.Lang, t.Title, t.Description)
+		_, err = tx.Exec(ctx, `INSERT INTO deal_translations (deal_id,lang,title,description,author_user_id) VALUES ($1,$2,$3,$4,$5)`, d.ID, t.Lang, t.Title, t.Description, d.CreatedByUserID)
 		if err != nil {
 			return err
 		}
 	}
+	if err := insertRevision(ctx, tx, d.ID, d.CreatedByUserID, nil); err != nil {
+		return err
+	}
 	return tx.Commit(ctx)
 }
 
 func (r *Repository) SubmissionDeals(ctx context.Context, userID int64) ([]models.Deal, error) {
-	rows, err := r.DB.Query(ctx, `SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code,d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at
+	rows, err := r.DB.Query(ctx, `SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code,d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at,d.approved_at,d.published_at
 	FROM deals d
 	JOIN countries co ON co.id=d.country_id
 	JOIN cities ci ON ci.id=d.city_id
@@ -302,14 +375,48 @@ func (r *Repository) SubmissionDeals(ctx context.Context, userID int64) ([]model
 	return scanDeals(rows)
 }
 
-func (r *Repository) UpdateSubmission(ctx context.Context, d *models.Deal) error {
-	_, err := r.DB.Exec(ctx, `UPDATE deals SET title=$1,description=$2,city_id=$3,category_id=$4,merchant_id=$5,deal_type_id=$6,start_at=$7,end_at=$8,image_url=$9,status='pending',updated_at=NOW() WHERE id=$10`,
+// UpdateSubmission saves d and its translations; a translation with an empty
+// title is removed. A changed translation becomes a draft by the editor; an
+// unchanged one keeps its status and author.
+func (r *Repository) UpdateSubmission(ctx context.Context, d *models.Deal, translations []models.DealTranslation, editorID int64, decide func(from models.DealStatus) (models.DealStatusChange, error)) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	ch, err := lockAndDecide(ctx, tx, d.ID, decide)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE deals SET title=$1,description=$2,city_id=$3,category_id=$4,merchant_id=$5,deal_type_id=$6,start_at=$7,end_at=$8,image_url=$9,updated_at=NOW() WHERE id=$10`,
 		d.Title, d.Description, d.CityID, d.CategoryID, d.MerchantID, d.DealTypeID, d.StartAt, d.EndAt, d.ImageURL, d.ID)
-	return err
+	if err != nil {
+		return err
+	}
+	for _, t := range translations {
+		if t.Title == "" {
+			_, err = tx.Exec(ctx, `DELETE FROM deal_translations WHERE deal_id=$1 AND lang=$2`, d.ID, t.Lang)
+		} else {
+			_, err = tx.Exec(ctx, `INSERT INTO deal_translations (deal_id,lang,title,description,author_user_id) VALUES ($1,$2,$3,$4,$5)
+			ON CONFLICT (deal_id,lang) DO UPDATE SET title=EXCLUDED.title,description=EXCLUDED.description,status='draft',
+			author_user_id=EXCLUDED.author_user_id,reviewed_by_user_id=NULL,reviewed_at=NULL,updated_at=NOW()
+			WHERE (deal_translations.title,deal_translations.description) IS DISTINCT FROM (EXCLUDED.title,EXCLUDED.description)`, d.ID, t.Lang, t.Title, t.Description, editorID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := applyStatusChange(ctx, tx, d.ID, ch); err != nil {
+		return err
+	}
+	if err := insertRevision(ctx, tx, d.ID, editorID, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *Repository) DealByID(ctx context.Context, id int64) (*models.Deal, error) {
-	rows, err := r.DB.Query(ctx, `SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code,d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at
+	rows, err := r.DB.Query(ctx, `SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code,d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at,d.approved_at,d.published_at
 	FROM deals d
 	JOIN countries co ON co.id=d.country_id
 	JOIN cities ci ON ci.id=d.city_id
@@ -331,15 +438,17 @@ func (r *Repository) DealByID(ctx context.Context, id int64) (*models.Deal, erro
 	return &ds[0], nil
 }
 
-func (r *Repository) PendingDeals(ctx context.Context) ([]models.Deal, error) {
-	rows, err := r.DB.Query(ctx, `SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code,d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at
+// PendingDeals lists the moderation queue, limited to countryIDs unless it is
+// nil.
+func (r *Repository) PendingDeals(ctx context.Context, countryIDs []int64) ([]models.Deal, error) {
+	rows, err := r.DB.Query(ctx, `SELECT d.id,d.title,d.slug,d.description,d.country_id,co.code,d.city_id,ci.name,d.category_id,ca.name,ca.slug,d.merchant_id,m.name,d.deal_type_id,dt.name,d.start_at,d.end_at,d.featured,d.image_url,d.status,d.created_by_user_id,d.rejection_reason,d.created_at,d.updated_at,d.approved_at,d.published_at
 	FROM deals d
 	JOIN countries co ON co.id=d.country_id
 	JOIN cities ci ON ci.id=d.city_id
 	JOIN categories ca ON ca.id=d.category_id
 	LEFT JOIN merchants m ON m.id=d.merchant_id
 	JOIN deal_types dt ON dt.id=d.deal_type_id
-	WHERE d.status='pending' ORDER BY d.created_at ASC`)
+	WHERE d.status='pending' AND ($1::bigint[] IS NULL OR d.country_id=ANY($1)) ORDER BY d.created_at ASC`, countryIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -347,24 +456,89 @@ func (r *Repository) PendingDeals(ctx context.Context) ([]models.Deal, error) {
 	return scanDeals(rows)
 }
 
-func (r *Repository) ModerateDeal(ctx context.Context, id int64, status models.DealStatus, reason *string) error {
-	_, err := r.DB.Exec(ctx, `UPDATE deals SET status=$1,rejection_reason=$2,updated_at=NOW() WHERE id=$3`, status, reason, id)
-	return err
+func (r *Repository) ChangeDealStatus(ctx context.Context, id int64, decide func(from models.DealStatus) (models.DealStatusChange, error)) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	ch, err := lockAndDecide(ctx, tx, id, decide)
+	if err != nil {
+		return err
+	}
+	if err := applyStatusChange(ctx, tx, id, ch); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func lockAndDecide(ctx context.Context, tx pgx.Tx, id int64, decide func(from models.DealStatus) (models.DealStatusChange, error)) (models.DealStatusChange, error) {
+	var from models.DealStatus
+	if err := tx.QueryRow(ctx, `SELECT status FROM deals WHERE id=$1 FOR UPDATE`, id).Scan(&from); err != nil {
+		return models.DealStatusChange{}, err
+	}
+	ch, err := decide(from)
+	ch.From = from
+	return ch, err
+}
+
+func applyStatusChange(ctx context.Context, tx pgx.Tx, id int64, ch models.DealStatusChange) error {
+	_, err := tx.Exec(ctx, `UPDATE deals SET status=$1,
+	rejection_reason=CASE WHEN $2::text IS NOT NULL THEN $2 WHEN $3 THEN NULL ELSE rejection_reason END,
+	approved_at=CASE WHEN $4 THEN NOW() ELSE approved_at END,
+	published_at=CASE WHEN $5 THEN NOW() ELSE published_at END,
+	updated_at=NOW() WHERE id=$6`, ch.To, ch.RejectionReason, ch.ClearRejection, ch.StampApproved, ch.StampPublished, id)
+	if err != nil {
+		return err
+	}
+	from := ch.From
+	for _, to := range ch.Path {
+		var reason *string
+		if to == models.DealRejected {
+			reason = ch.RejectionReason
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO deal_status_history (deal_id,from_status,to_status,actor,user_id,reason) VALUES ($1,$2,$3,$4,$5,$6)`, id, from, to, ch.Actor, ch.UserID, reason); err != nil {
+			return err
+		}
+		from = to
+	}
+	return nil
+}
+
+// DealStatusHistory lists the status changes of a deal, newest first.
+func (r *Repository) DealStatusHistory(ctx context.Context, dealID int64) ([]models.DealStatusEvent, error) {
+	rows, err := r.DB.Query(ctx, `SELECT h.id,h.deal_id,h.from_status,h.to_status,h.actor,h.user_id,COALESCE(u.name,''),h.reason,h.created_at
+	FROM deal_status_history h
+	LEFT JOIN users u ON u.id=h.user_id
+	WHERE h.deal_id=$1 ORDER BY h.id DESC`, dealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.DealStatusEvent
+	for rows.Next() {
+		var e models.DealStatusEvent
+		if err := rows.Scan(&e.ID, &e.DealID, &e.From, &e.To, &e.Actor, &e.UserID, &e.UserName, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
 }
 
-func (r *Repository) DashboardCounts(ctx context.Context) (int, int, error) {
+func (r *Repository) DashboardCounts(ctx context.Context, countryIDs []int64) (int, int, error) {
 	var pending, published int
-	if err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM deals WHERE status='pending'`).Scan(&pending); err != nil {
+	if err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM deals WHERE status='pending' AND ($1::bigint[] IS NULL OR country_id=ANY($1))`, countryIDs).Scan(&pending); err != nil {
 		return 0, 0, err
 	}
-	if err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM deals WHERE status='published'`).Scan(&published); err != nil {
+	if err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM deals WHERE status='published' AND ($1::bigint[] IS NULL OR country_id=ANY($1))`, countryIDs).Scan(&published); err != nil {
 		return 0, 0, err
 	}
 	return pending, published, nil
 }
 
 func (r *Repository) Users(ctx context.Context) ([]models.User, error) {
-	rows, err := r.DB.Query(ctx, `SELECT id,email,password_hash,name,role,created_at FROM users ORDER BY created_at DESC`)
+	rows, err := r.DB.Query(ctx, `SELECT id,email,password_hash,name,role,created_at,email_verified_at,COALESCE(totp_secret,''),totp_enabled_at,failed_login_count,last_failed_login_at,CASE WHEN locked_until > NOW() THEN locked_until END,preferred_lang,ARRAY(SELECT country_id FROM user_countries uc WHERE uc.user_id=users.id ORDER BY country_id),all_countries FROM users ORDER BY created_at DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -372,7 +546,7 @@ func (r *Repository) Users(ctx context.Context) ([]models.User, error) {
 	var out []models.User
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt, &u.EmailVerifiedAt, &u.TOTPSecret, &u.TOTPEnabledAt, &u.FailedLoginCount, &u.LastFailedLoginAt, &u.LockedUntil, &u.PreferredLang, &u.CountryIDs, &u.AllCountries); err != nil {
 			return nil, err
 		}
 		out = append(out, u)
@@ -380,6 +554,11 @@ func (r *Repository) Users(ctx context.Context) ([]models.User, error) {
 	return out, rows.Err()
 }
 
+func (r *Repository) SetUserLang(ctx context.Context, id int64, lang string) error {
+	_, err := r.DB.Exec(ctx, `UPDATE users SET preferred_lang=$2 WHERE id=$1`, id, lang)
+	return err
+}
+
 func (r *Repository) UpdateUserRole(ctx context.Context, id int64, role models.UserRole) error {
 	_, err := r.DB.Exec(ctx, `UPDATE users SET role=$1 WHERE id=$2`, role, id)
 	return err
@@ -420,7 +599,10 @@ func (r *Repository) UpsertTranslation(ctx context.Context, dealID int64, lang,
 }
 
 func (r *Repository) CreateCountry(ctx context.Context, c *models.Country) error {
-	return r.DB.QueryRow(ctx, `INSERT INTO countries (code,name,default_language) VALUES ($1,$2,$3) RETURNING id`, c.Code, c.Name, c.DefaultLanguage).Scan(&c.ID)
+	if c.Languages == nil {
+		c.Languages = []string{}
+	}
+	return r.DB.QueryRow(ctx, `INSERT INTO countries (code,name,default_language,languages) VALUES ($1,$2,$3,$4) RETURNING id`, c.Code, c.Name, c.DefaultLanguage, c.Languages).Scan(&c.ID)
 }
 
 func (r *Repository) CreateCity(ctx context.Context, c *models.City) error {
@@ -439,14 +621,52 @@ func (r *Repository) CreateDealType(ctx context.Context, dt *models.DealType) er
 	return r.DB.QueryRow(ctx, `INSERT INTO deal_types (code,name) VALUES ($1,$2) RETURNING id`, dt.Code, dt.Name).Scan(&dt.ID)
 }
 
-func (r *Repository) UpdateDealStatusDirect(ctx context.Context, id int64, status models.DealStatus) error {
-	_, err := r.DB.Exec(ctx, `UPDATE deals SET status=$1, updated_at=NOW() WHERE id=$2`, status, id)
-	return err
+// EndedDealIDs lists pending, rejected, approved and published deals whose
+// end has passed.
+func (r *Repository) EndedDealIDs(ctx context.Context) ([]int64, error) {
+	return r.dealIDs(ctx, `SELECT id FROM deals WHERE status IN ('pending','rejected','approved','published') AND end_at < NOW() ORDER BY id`)
 }
 
-func (r *Repository) TouchExpiredDeals(ctx context.Context) error {
-	_, err := r.DB.Exec(ctx, `UPDATE deals SET status='expired' WHERE status='published' AND end_at < NOW()`)
-	return err
+// ScheduledDealIDs lists approved deals whose start has arrived and that
+// have not ended.
+func (r *Repository) ScheduledDealIDs(ctx context.Context) ([]int64, error) {
+	return r.dealIDs(ctx, `SELECT id FROM deals WHERE status='approved' AND start_at <= NOW() AND end_at > NOW() ORDER BY id`)
+}
+
+func (r *Repository) dealIDs(ctx context.Context, q string) ([]int64, error) {
+	rows, err := r.DB.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// ImageURLs returns the image URLs used by deals or any of their revisions.
+func (r *Repository) ImageURLs(ctx context.Context) (map[string]bool, error) {
+	rows, err := r.DB.Query(ctx, `SELECT image_url FROM deals WHERE image_url<>''
+	UNION SELECT snapshot->>'image_url' FROM deal_revisions WHERE snapshot->>'image_url'<>''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]bool{}
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		out[u] = true
+	}
+	return out, rows.Err()
 }
 
 func DateNowPlus(days int) time.Time { return time.Now().AddDate(0, 0, days) }
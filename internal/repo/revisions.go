@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+
+	"go-next-cms/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func snapshotDeal(ctx context.Context, tx pgx.Tx, dealID int64) (models.DealSnapshot, error) {
+	var s models.DealSnapshot
+	err := tx.QueryRow(ctx, `SELECT title,description,city_id,category_id,merchant_id,deal_type_id,start_at,end_at,COALESCE(image_url,'') FROM deals WHERE id=$1`, dealID).
+		Scan(&s.Title, &s.Description, &s.CityID, &s.CategoryID, &s.MerchantID, &s.DealTypeID, &s.StartAt, &s.EndAt, &s.ImageURL)
+	if err != nil {
+		return s, err
+	}
+	rows, err := tx.Query(ctx, `SELECT lang,title,description,status,author_user_id,reviewed_by_user_id,reviewed_at FROM deal_translations WHERE deal_id=$1 ORDER BY lang`, dealID)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t models.SnapshotTranslation
+		if err := rows.Scan(&t.Lang, &t.Title, &t.Description, &t.Status, &t.AuthorID, &t.ReviewedByID, &t.ReviewedAt); err != nil {
+			return s, err
+		}
+		s.Translations = append(s.Translations, t)
+	}
+	return s, rows.Err()
+}
+
+func insertRevision(ctx context.Context, tx pgx.Tx, dealID, userID int64, restoredFrom *int64) error {
+	s, err := snapshotDeal(ctx, tx, dealID)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO deal_revisions (deal_id,snapshot,created_by_user_id,restored_from_id) VALUES ($1,$2,$3,$4)`, dealID, b, userID, restoredFrom)
+	return err
+}
+
+func (r *Repository) DealRevisions(ctx context.Context, dealID int64) ([]models.DealRevision, error) {
+	rows, err := r.DB.Query(ctx, `SELECT rv.id,rv.deal_id,rv.snapshot,rv.created_by_user_id,u.name,rv.restored_from_id,rv.created_at
+	FROM deal_revisions rv
+	JOIN users u ON u.id=rv.created_by_user_id
+	WHERE rv.deal_id=$1 ORDER BY rv.id DESC`, dealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.DealRevision
+	for rows.Next() {
+		var rv models.DealRevision
+		var raw []byte
+		if err := rows.Scan(&rv.ID, &rv.DealID, &raw, &rv.CreatedByUserID, &rv.CreatedByName, &rv.RestoredFromID, &rv.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &rv.Snapshot); err != nil {
+			return nil, err
+		}
+		out = append(out, rv)
+	}
+	return out, rows.Err()
+}
+
+// RestoreDealRevision writes the content of revision revID back onto the deal
+// and records the result as a new revision; earlier revisions are kept. The
+// status change decide returns is applied as for any other edit. Translations
+// whose text the restore leaves unchanged keep their review state; changed
+// ones get the state stored in the revision, or become drafts by userID for
+// revisions from before review states were stored.
+func (r *Repository) RestoreDealRevision(ctx context.Context, dealID, revID, userID int64, decide func(from models.DealStatus) (models.DealStatusChange, error)) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	ch, err := lockAndDecide(ctx, tx, dealID, decide)
+	if err != nil {
+		return err
+	}
+	var raw []byte
+	if err := tx.QueryRow(ctx, `SELECT snapshot FROM deal_revisions WHERE id=$1 AND deal_id=$2`, revID, dealID).Scan(&raw); err != nil {
+		return err
+	}
+	var s models.DealSnapshot
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE deals SET title=$1,description=$2,city_id=$3,category_id=$4,merchant_id=$5,deal_type_id=$6,start_at=$7,end_at=$8,image_url=$9,updated_at=NOW() WHERE id=$10`,
+		s.Title, s.Description, s.CityID, s.CategoryID, s.MerchantID, s.DealTypeID, s.StartAt, s.EndAt, s.ImageURL, dealID)
+	if err != nil {
+		return err
+	}
+	langs := make([]string, len(s.Translations))
+	for i, t := range s.Translations {
+		langs[i] = t.Lang
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM deal_translations WHERE deal_id=$1 AND NOT lang=ANY($2)`, dealID, langs); err != nil {
+		return err
+	}
+	for _, t := range s.Translations {
+		if t.Status == "" {
+			t.Status, t.AuthorID, t.ReviewedByID, t.ReviewedAt = models.TranslationDraft, &userID, nil, nil
+		}
+		// Users named by the revision may have been deleted since.
+		_, err := tx.Exec(ctx, `INSERT INTO deal_translations (deal_id,lang,title,description,status,author_user_id,reviewed_by_user_id,reviewed_at)
+		VALUES ($1,$2,$3,$4,$5,(SELECT id FROM users WHERE id=$6),(SELECT id FROM users WHERE id=$7),$8)
+		ON CONFLICT (deal_id,lang) DO UPDATE SET title=EXCLUDED.title,description=EXCLUDED.description,status=EXCLUDED.status,
+		author_user_id=EXCLUDED.author_user_id,reviewed_by_user_id=EXCLUDED.reviewed_by_user_id,reviewed_at=EXCLUDED.reviewed_at,updated_at=NOW()
+		WHERE (deal_translations.title,deal_translations.description) IS DISTINCT FROM (EXCLUDED.title,EXCLUDED.description)`,
+			dealID, t.Lang, t.Title, t.Description, t.Status, t.AuthorID, t.ReviewedByID, t.ReviewedAt)
+		if err != nil {
+			return err
+		}
+	}
+	if err := applyStatusChange(ctx, tx, dealID, ch); err != nil {
+		return err
+	}
+	if err := insertRevision(ctx, tx, dealID, userID, &revID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"fmt"
+
+	"go-next-cms/internal/models"
+)
+
+type searchSQL struct {
+	Join    string
+	Where   string
+	Rank    string
+	Snippet string
+}
+
+var noSearch = searchSQL{Rank: "0", Snippet: "''"}
+
+// searchClause matches the full-text document first and falls back to
+// trigram word similarity so misspelled queries still find deals. The snippet
+// is cut from the description in the language shown, which needs the tr join
+// of dealJoins.
+func searchClause(idx int) searchSQL {
+	tsq := fmt.Sprintf("(websearch_to_tsquery('english', $%d) || websearch_to_tsquery('simple', $%d))", idx, idx)
+	return searchSQL{
+		Join:    "JOIN deal_search s ON s.deal_id=d.id",
+		Where:   fmt.Sprintf("(s.document @@ %s OR $%d <%% s.content)", tsq, idx),
+		Rank:    fmt.Sprintf("(ts_rank_cd(s.document, %s) + word_similarity($%d, s.content))", tsq, idx),
+		Snippet: fmt.Sprintf("ts_headline('simple', COALESCE(tr.description, d.description), %s, 'StartSel=%s, StopSel=%s, MaxFragments=2, MaxWords=20, MinWords=5')", tsq, models.SnippetMarkStart, models.SnippetMarkStop),
+	}
+}
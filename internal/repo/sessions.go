@@ -0,0 +1,87 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-next-cms/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SessionStorage implements fiber.Storage on the sessions table so sessions
+// survive restarts and are shared between replicas.
+type SessionStorage struct{ Repo *Repository }
+
+func (r *Repository) SessionStorage() *SessionStorage { return &SessionStorage{Repo: r} }
+
+func (s *SessionStorage) Get(key string) ([]byte, error) {
+	var data []byte
+	err := s.Repo.DB.QueryRow(context.Background(), `SELECT data FROM sessions WHERE sid=$1 AND (expires_at IS NULL OR expires_at > NOW())`, key).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *SessionStorage) Set(key string, val []byte, exp time.Duration) error {
+	var expiresAt *time.Time
+	if exp > 0 {
+		t := time.Now().Add(exp)
+		expiresAt = &t
+	}
+	_, err := s.Repo.DB.Exec(context.Background(), `INSERT INTO sessions (sid,data,expires_at) VALUES ($1,$2,$3)
+	ON CONFLICT (sid) DO UPDATE SET data=EXCLUDED.data,expires_at=EXCLUDED.expires_at,last_seen_at=NOW()`, key, val, expiresAt)
+	return err
+}
+
+func (s *SessionStorage) Delete(key string) error {
+	_, err := s.Repo.DB.Exec(context.Background(), `DELETE FROM sessions WHERE sid=$1`, key)
+	return err
+}
+
+func (s *SessionStorage) Reset() error {
+	_, err := s.Repo.DB.Exec(context.Background(), `DELETE FROM sessions`)
+	return err
+}
+
+func (s *SessionStorage) Close() error { return nil }
+
+func (r *Repository) AssignSession(ctx context.Context, sid string, userID int64, userAgent, ip string) error {
+	_, err := r.DB.Exec(ctx, `UPDATE sessions SET user_id=$1,user_agent=$2,ip=$3 WHERE sid=$4`, userID, userAgent, ip, sid)
+	return err
+}
+
+func (r *Repository) UserSessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	rows, err := r.DB.Query(ctx, `SELECT id,sid,user_id,user_agent,ip,expires_at,created_at,last_seen_at FROM sessions
+	WHERE user_id=$1 AND (expires_at IS NULL OR expires_at > NOW()) ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.Session
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.SID, &s.UserID, &s.UserAgent, &s.IP, &s.ExpiresAt, &s.CreatedAt, &s.LastSeenAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) DeleteUserSession(ctx context.Context, userID, id int64) error {
+	_, err := r.DB.Exec(ctx, `DELETE FROM sessions WHERE id=$1 AND user_id=$2`, id, userID)
+	return err
+}
+
+func (r *Repository) DeleteUserSessions(ctx context.Context, userID int64) error {
+	_, err := r.DB.Exec(ctx, `DELETE FROM sessions WHERE user_id=$1`, userID)
+	return err
+}
+
+func (r *Repository) DeleteExpiredSessions(ctx context.Context) error {
+	_, err := r.DB.Exec(ctx, `DELETE FROM sessions WHERE expires_at < NOW()`)
+	return err
+}
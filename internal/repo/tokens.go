@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"context"
+
+	"go-next-cms/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const apiTokenColumns = `id,user_id,name,prefix,token_hash,scopes,rate_limit,expires_at,revoked_at,last_used_at,created_at`
+
+func scanAPIToken(row pgx.Row) (*models.APIToken, error) {
+	var t models.APIToken
+	var scopes []string
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.TokenHash, &scopes, &t.RateLimit, &t.ExpiresAt, &t.RevokedAt, &t.LastUsedAt, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	for _, s := range scopes {
+		t.Scopes = append(t.Scopes, models.APIScope(s))
+	}
+	return &t, nil
+}
+
+func (r *Repository) CreateAPIToken(ctx context.Context, t *models.APIToken) error {
+	scopes := make([]string, 0, len(t.Scopes))
+	for _, s := range t.Scopes {
+		scopes = append(scopes, string(s))
+	}
+	return r.DB.QueryRow(ctx, `INSERT INTO api_tokens (user_id,name,prefix,token_hash,scopes,rate_limit,expires_at) VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id,created_at`,
+		t.UserID, t.Name, t.Prefix, t.TokenHash, scopes, t.RateLimit, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+}
+
+func (r *Repository) APITokenByPrefix(ctx context.Context, prefix string) (*models.APIToken, error) {
+	return scanAPIToken(r.DB.QueryRow(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE prefix=$1`, prefix))
+}
+
+func (r *Repository) APITokensByUser(ctx context.Context, userID int64) ([]models.APIToken, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id=$1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) RevokeAPIToken(ctx context.Context, userID, id int64) error {
+	_, err := r.DB.Exec(ctx, `UPDATE api_tokens SET revoked_at=NOW() WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`, id, userID)
+	return err
+}
+
+func (r *Repository) TouchAPIToken(ctx context.Context, id int64) error {
+	_, err := r.DB.Exec(ctx, `UPDATE api_tokens SET last_used_at=NOW() WHERE id=$1`, id)
+	return err
+}
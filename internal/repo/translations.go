@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"go-next-cms/internal/models"
+)
+
+// MissingTranslations lists up to limit live deals lacking a translation into
+// one of their country's languages other than the default; countries without
+// a language list use langs. Pairs that failed maxAttempts times, or whose
+// next attempt is not due, are left out.
+func (r *Repository) MissingTranslations(ctx context.Context, langs []string, maxAttempts, limit int) ([]models.MissingTranslation, error) {
+	rows, err := r.DB.Query(ctx, `SELECT d.id,co.default_language,l.lang,d.title,d.description
+	FROM deals d
+	JOIN countries co ON co.id=d.country_id
+	CROSS JOIN LATERAL unnest(CASE WHEN cardinality(co.languages)>0 THEN co.languages ELSE $1::text[] END) AS l(lang)
+	WHERE d.status IN ('pending','approved','published') AND l.lang<>co.default_language
+	AND NOT EXISTS (SELECT 1 FROM deal_translations t WHERE t.deal_id=d.id AND t.lang=l.lang AND t.title<>'')
+	AND NOT EXISTS (SELECT 1 FROM translation_attempts a WHERE a.deal_id=d.id AND a.lang=l.lang AND (a.attempts>=$2 OR a.next_attempt_at>NOW()))
+	ORDER BY d.id, l.lang LIMIT $3`, langs, maxAttempts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.MissingTranslation
+	for rows.Next() {
+		var m models.MissingTranslation
+		if err := rows.Scan(&m.DealID, &m.From, &m.To, &m.Title, &m.Description); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// TranslationFailed records a failed machine translation of deal dealID into
+// lang. The next attempt waits backoff, doubled for each earlier failure.
+func (r *Repository) TranslationFailed(ctx context.Context, dealID int64, lang, reason string, backoff time.Duration) error {
+	_, err := r.DB.Exec(ctx, `INSERT INTO translation_attempts (deal_id,lang,attempts,last_error,next_attempt_at) VALUES ($1,$2,1,$3,NOW()+make_interval(secs => $4))
+	ON CONFLICT (deal_id,lang) DO UPDATE SET attempts=translation_attempts.attempts+1,last_error=EXCLUDED.last_error,
+	next_attempt_at=NOW()+make_interval(secs => $4::float8 * power(2::float8, translation_attempts.attempts))`, dealID, lang, reason, backoff.Seconds())
+	return err
+}
+
+// SaveMachineTranslation stores a machine draft unless someone wrote a
+// translation in the meantime, and forgets earlier failed attempts.
+func (r *Repository) SaveMachineTranslation(ctx context.Context, t models.DealTranslation) error {
+	_, err := r.DB.Exec(ctx, `WITH cleared AS (DELETE FROM translation_attempts WHERE deal_id=$1 AND lang=$2)
+	INSERT INTO deal_translations (deal_id,lang,title,description,status) VALUES ($1,$2,$3,$4,'machine')
+	ON CONFLICT (deal_id,lang) DO UPDATE SET title=EXCLUDED.title,description=EXCLUDED.description,status='machine',author_user_id=NULL,updated_at=NOW()
+	WHERE deal_translations.title=''`, t.DealID, t.Lang, t.Title, t.Description)
+	return err
+}
+
+// translationQueueFrom and translationQueueWhere select the translation
+// queue: live deals and the languages of their country other than the
+// default whose translation is not reviewed. Countries without a language
+// list use $1; $2 to $4 filter by country IDs, language and status unless
+// nil or empty.
+const translationQueueFrom = `FROM deals d
+	JOIN countries co ON co.id=d.country_id
+	CROSS JOIN LATERAL unnest(CASE WHEN cardinality(co.languages)>0 THEN co.languages ELSE $1::text[] END) AS l(lang)
+	LEFT JOIN deal_translations t ON t.deal_id=d.id AND t.lang=l.lang`
+
+const translationQueueWhere = `WHERE d.status IN ('pending','approved','published') AND l.lang<>co.default_language
+	AND ($2::bigint[] IS NULL OR d.country_id=ANY($2))
+	AND ($3='' OR l.lang=$3)
+	AND (t.title IS NULL OR t.title='' OR t.status<>'reviewed')
+	AND ($4='' OR $4=CASE WHEN t.title IS NULL OR t.title='' THEN 'missing' ELSE t.status END)`
+
+// TranslationQueue lists a page of the deals whose translation into one of
+// their country's languages other than the default is not reviewed, soonest
+// ending first, with the total; countries without a language list use langs.
+// An empty lang or status does not filter, and neither does a nil countryIDs.
+func (r *Repository) TranslationQueue(ctx context.Context, countryIDs []int64, langs []string, lang string, status models.TranslationStatus, page, pageSize int) ([]models.TranslationTask, int, error) {
+	rows, err := r.DB.Query(ctx, `SELECT d.id,d.country_id,co.code,d.title,d.description,l.lang,CASE WHEN t.title IS NULL OR t.title='' THEN 'missing' ELSE t.status END,
+	COALESCE(t.title,''),COALESCE(t.description,''),COALESCE(u.name,''),t.updated_at,COUNT(*) OVER()
+	`+translationQueueFrom+`
+	LEFT JOIN users u ON u.id=t.author_user_id
+	`+translationQueueWhere+`
+	ORDER BY d.end_at ASC, d.id, l.lang LIMIT $5 OFFSET $6`, langs, countryIDs, lang, string(status), pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var out []models.TranslationTask
+	total := 0
+	for rows.Next() {
+		var x models.TranslationTask
+		if err := rows.Scan(&x.DealID, &x.CountryID, &x.CountryCode, &x.DealTitle, &x.DealDescription, &x.Lang, &x.Status, &x.Title, &x.Description, &x.AuthorName, &x.UpdatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, x)
+	}
+	return out, total, rows.Err()
+}
+
+// CountTranslationQueue counts what TranslationQueue lists.
+func (r *Repository) CountTranslationQueue(ctx context.Context, countryIDs []int64, langs []string, lang string, status models.TranslationStatus) (int, error) {
+	var n int
+	err := r.DB.QueryRow(ctx, `SELECT COUNT(*) `+translationQueueFrom+` `+translationQueueWhere, langs, countryIDs, lang, string(status)).Scan(&n)
+	return n, err
+}
+
+// SaveTranslationDraft stores a translation from the queue without reviewing
+// it. Changed text becomes a draft by authorID; unchanged text is left alone.
+func (r *Repository) SaveTranslationDraft(ctx context.Context, t models.DealTranslation, authorID int64) error {
+	_, err := r.DB.Exec(ctx, `INSERT INTO deal_translations (deal_id,lang,title,description,author_user_id) VALUES ($1,$2,$3,$4,$5)
+	ON CONFLICT (deal_id,lang) DO UPDATE SET title=EXCLUDED.title,description=EXCLUDED.description,status='draft',
+	author_user_id=EXCLUDED.author_user_id,reviewed_by_user_id=NULL,reviewed_at=NULL,updated_at=NOW()
+	WHERE (deal_translations.title,deal_translations.description) IS DISTINCT FROM (EXCLUDED.title,EXCLUDED.description)`, t.DealID, t.Lang, t.Title, t.Description, authorID)
+	return err
+}
+
+// ConfirmTranslation marks a translation reviewed by reviewerID if its
+// stored text is t's and someone else wrote it, and reports whether it did.
+func (r *Repository) ConfirmTranslation(ctx context.Context, t models.DealTranslation, reviewerID int64) (bool, error) {
+	tag, err := r.DB.Exec(ctx, `UPDATE deal_translations SET status='reviewed',reviewed_by_user_id=$5,reviewed_at=NOW(),updated_at=NOW()
+	WHERE deal_id=$1 AND lang=$2 AND title=$3 AND description=$4 AND author_user_id IS DISTINCT FROM $5`, t.DealID, t.Lang, t.Title, t.Description, reviewerID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReviewTranslation stores a translation as reviewed by reviewerID. Changed
+// text is credited to the reviewer; unchanged text keeps its author.
+func (r *Repository) ReviewTranslation(ctx context.Context, t models.DealTranslation, reviewerID int64) error {
+	_, err := r.DB.Exec(ctx, `INSERT INTO deal_translations (deal_id,lang,title,description,status,author_user_id,reviewed_by_user_id,reviewed_at) VALUES ($1,$2,$3,$4,'reviewed',$5,$5,NOW())
+	ON CONFLICT (deal_id,lang) DO UPDATE SET title=EXCLUDED.title,description=EXCLUDED.description,status='reviewed',
+	author_user_id=CASE WHEN (deal_translations.title,deal_translations.description) IS DISTINCT FROM (EXCLUDED.title,EXCLUDED.description) THEN EXCLUDED.author_user_id ELSE deal_translations.author_user_id END,
+	reviewed_by_user_id=EXCLUDED.reviewed_by_user_id,reviewed_at=NOW(),updated_at=NOW()`, t.DealID, t.Lang, t.Title, t.Description, reviewerID)
+	return err
+}
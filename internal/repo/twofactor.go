@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+)
+
+// EnableTOTP stores the confirmed, sealed secret and replaces any previous recovery
+// codes with codeHashes.
+func (r *Repository) EnableTOTP(ctx context.Context, userID int64, secret string, codeHashes []string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `UPDATE users SET totp_secret=$1,totp_enabled_at=NOW(),totp_last_step=NULL WHERE id=$2`, secret, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO user_recovery_codes (user_id,code_hash) VALUES ($1,$2)`, userID, h); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// SetTOTPSecret replaces the stored secret, as when sealing a legacy one.
+func (r *Repository) SetTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	_, err := r.DB.Exec(ctx, `UPDATE users SET totp_secret=$1 WHERE id=$2`, secret, userID)
+	return err
+}
+
+func (r *Repository) DisableTOTP(ctx context.Context, userID int64) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `UPDATE users SET totp_secret=NULL,totp_enabled_at=NULL,totp_last_step=NULL WHERE id=$1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep records step as consumed, returning false if it (or a later
+// step) was already used.
+func (r *Repository) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	tag, err := r.DB.Exec(ctx, `UPDATE users SET totp_last_step=$1 WHERE id=$2 AND (totp_last_step IS NULL OR totp_last_step < $1)`, step, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	tag, err := r.DB.Exec(ctx, `UPDATE user_recovery_codes SET used_at=NOW() WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *Repository) RemainingRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	var n int
+	err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id=$1 AND used_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
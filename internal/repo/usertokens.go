@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"go-next-cms/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateUserToken stores a new single-use token and invalidates any unused
+// tokens the user still holds for the same purpose.
+func (r *Repository) CreateUserToken(ctx context.Context, userID int64, purpose models.UserTokenPurpose, hash string, expiresAt time.Time) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `UPDATE user_tokens SET used_at=NOW() WHERE user_id=$1 AND purpose=$2 AND used_at IS NULL`, userID, purpose); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO user_tokens (user_id,purpose,token_hash,expires_at) VALUES ($1,$2,$3,$4)`, userID, purpose, hash, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func consumeUserToken(ctx context.Context, tx pgx.Tx, purpose models.UserTokenPurpose, hash string) (int64, error) {
+	var userID int64
+	err := tx.QueryRow(ctx, `UPDATE user_tokens SET used_at=NOW() WHERE token_hash=$1 AND purpose=$2 AND used_at IS NULL AND expires_at > NOW() RETURNING user_id`, hash, purpose).Scan(&userID)
+	return userID, err
+}
+
+func (r *Repository) VerifyEmailWithToken(ctx context.Context, hash string) (int64, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	userID, err := consumeUserToken(ctx, tx, models.TokenVerifyEmail, hash)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET email_verified_at=COALESCE(email_verified_at, NOW()) WHERE id=$1`, userID); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+// ResetPasswordWithToken sets a new password hash and signs the user out of
+// every session.
+func (r *Repository) ResetPasswordWithToken(ctx context.Context, hash, passwordHash string) (int64, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	userID, err := consumeUserToken(ctx, tx, models.TokenResetPassword, hash)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET password_hash=$1 WHERE id=$2`, passwordHash, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM sessions WHERE user_id=$1`, userID); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+func (r *Repository) DeleteStaleUserTokens(ctx context.Context) error {
+	_, err := r.DB.Exec(ctx, `DELETE FROM user_tokens WHERE expires_at < NOW() - INTERVAL '7 days'`)
+	return err
+}
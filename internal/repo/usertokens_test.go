@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go-next-cms/internal/auth"
+	"go-next-cms/internal/db"
+	"go-next-cms/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// testRepo connects to the migrated database named by TEST_DATABASE_URL and
+// skips the test when it is unset.
+func testRepo(t *testing.T) *Repository {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := db.NewPool(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return New(pool)
+}
+
+// testUser creates a throwaway user that is deleted, with its tokens and
+// sessions, when the test ends.
+func testUser(t *testing.T, r *Repository) *models.User {
+	t.Helper()
+	ctx := context.Background()
+	u := &models.User{Email: fmt.Sprintf("tokens-%d@example.com", time.Now().UnixNano()), PasswordHash: "old", Name: "Token Test", Role: models.RoleSubmitter}
+	if err := r.CreateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.DB.Exec(context.Background(), `DELETE FROM users WHERE id=$1`, u.ID) })
+	return u
+}
+
+func issueToken(t *testing.T, r *Repository, userID int64, purpose models.UserTokenPurpose, ttl time.Duration) string {
+	t.Helper()
+	raw, hash, err := auth.NewOneTimeToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateUserToken(context.Background(), userID, purpose, hash, time.Now().Add(ttl)); err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyEmailWithToken(t *testing.T) {
+	r := testRepo(t)
+	ctx := context.Background()
+	u := testUser(t, r)
+	raw := issueToken(t, r, u.ID, models.TokenVerifyEmail, time.Hour)
+
+	if _, err := r.ResetPasswordWithToken(ctx, auth.HashToken(raw), "new"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("verification token used as reset token: err = %v, want %v", err, pgx.ErrNoRows)
+	}
+	id, err := r.VerifyEmailWithToken(ctx, auth.HashToken(raw))
+	if err != nil || id != u.ID {
+		t.Fatalf("VerifyEmailWithToken = %d, %v; want %d, nil", id, err, u.ID)
+	}
+	got, err := r.UserByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.EmailVerifiedAt == nil {
+		t.Error("email_verified_at not set")
+	}
+	if _, err := r.VerifyEmailWithToken(ctx, auth.HashToken(raw)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("second use: err = %v, want %v", err, pgx.ErrNoRows)
+	}
+}
+
+func TestResetPasswordWithToken(t *testing.T) {
+	r := testRepo(t)
+	ctx := context.Background()
+	u := testUser(t, r)
+	if _, err := r.DB.Exec(ctx, `INSERT INTO sessions (sid,data,user_id) VALUES ($1,'',$2)`, fmt.Sprintf("tokens-%d", u.ID), u.ID); err != nil {
+		t.Fatal(err)
+	}
+	raw := issueToken(t, r, u.ID, models.TokenResetPassword, time.Hour)
+
+	if _, err := r.VerifyEmailWithToken(ctx, auth.HashToken(raw)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("reset token used as verification token: err = %v, want %v", err, pgx.ErrNoRows)
+	}
+	id, err := r.ResetPasswordWithToken(ctx, auth.HashToken(raw), "new")
+	if err != nil || id != u.ID {
+		t.Fatalf("ResetPasswordWithToken = %d, %v; want %d, nil", id, err, u.ID)
+	}
+	got, err := r.UserByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PasswordHash != "new" {
+		t.Errorf("password hash = %q, want %q", got.PasswordHash, "new")
+	}
+	var sessions int
+	if err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM sessions WHERE user_id=$1`, u.ID).Scan(&sessions); err != nil {
+		t.Fatal(err)
+	}
+	if sessions != 0 {
+		t.Errorf("%d sessions left after reset, want 0", sessions)
+	}
+	if _, err := r.ResetPasswordWithToken(ctx, auth.HashToken(raw), "newer"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("second use: err = %v, want %v", err, pgx.ErrNoRows)
+	}
+}
+
+func TestUserTokenExpiry(t *testing.T) {
+	r := testRepo(t)
+	ctx := context.Background()
+	u := testUser(t, r)
+	raw := issueToken(t, r, u.ID, models.TokenResetPassword, time.Hour)
+	// Expire it on the database clock so the test does not depend on the
+	// server's time zone.
+	if _, err := r.DB.Exec(ctx, `UPDATE user_tokens SET expires_at=NOW() - INTERVAL '1 minute' WHERE token_hash=$1`, auth.HashToken(raw)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ResetPasswordWithToken(ctx, auth.HashToken(raw), "new"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expired token: err = %v, want %v", err, pgx.ErrNoRows)
+	}
+}
+
+func TestCreateUserTokenReplacesUnused(t *testing.T) {
+	r := testRepo(t)
+	ctx := context.Background()
+	u := testUser(t, r)
+	first := issueToken(t, r, u.ID, models.TokenVerifyEmail, time.Hour)
+	reset := issueToken(t, r, u.ID, models.TokenResetPassword, time.Hour)
+	second := issueToken(t, r, u.ID, models.TokenVerifyEmail, time.Hour)
+
+	if _, err := r.VerifyEmailWithToken(ctx, auth.HashToken(first)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("replaced token: err = %v, want %v", err, pgx.ErrNoRows)
+	}
+	if _, err := r.VerifyEmailWithToken(ctx, auth.HashToken(second)); err != nil {
+		t.Errorf("latest token: %v", err)
+	}
+	if _, err := r.ResetPasswordWithToken(ctx, auth.HashToken(reset), "new"); err != nil {
+		t.Errorf("token for another purpose was replaced: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go-next-cms/internal/auth"
+	"go-next-cms/internal/mail"
+	"go-next-cms/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	verifyTokenTTL = 48 * time.Hour
+	resetTokenTTL  = time.Hour
+)
+
+var ErrInvalidToken = errors.New("link is invalid or has expired")
+
+func (s *Service) SendVerification(ctx context.Context, u *models.User) error {
+	raw, hash, err := auth.NewOneTimeToken()
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.CreateUserToken(ctx, u.ID, models.TokenVerifyEmail, hash, time.Now().Add(verifyTokenTTL)); err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Confirm your email address",
+		Body:    fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening this link:\n%s/account/verify?token=%s\n\nThe link expires in 48 hours.\n", u.Name, s.BaseURL, raw),
+	})
+}
+
+func (s *Service) VerifyEmail(ctx context.Context, raw string) error {
+	_, err := s.Repo.VerifyEmailWithToken(ctx, auth.HashToken(raw))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidToken
+	}
+	return err
+}
+
+// resetMailTimeout bounds the lookup and send behind a reset request.
+const resetMailTimeout = time.Minute
+
+// RequestPasswordReset mails a reset link if email belongs to an account. The
+// lookup and send run in the background and failures are only logged, so
+// neither the answer nor its timing tells whether an account exists.
+func (s *Service) RequestPasswordReset(email string) {
+	email = strings.Clone(email)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), resetMailTimeout)
+		defer cancel()
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("password reset: %v", err)
+		}
+	}()
+}
+
+func (s *Service) sendPasswordReset(ctx context.Context, email string) error {
+	u, err := s.Repo.UserByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	raw, hash, err := auth.NewOneTimeToken()
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.CreateUserToken(ctx, u.ID, models.TokenResetPassword, hash, time.Now().Add(resetTokenTTL)); err != nil {
+		return err
+	}
+	if err := s.Mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Hi %s,\n\nReset your password by opening this link:\n%s/account/reset?token=%s\n\nThe link expires in one hour. If you did not ask for a reset you can ignore this email.\n", u.Name, s.BaseURL, raw),
+	}); err != nil {
+		return fmt.Errorf("send to user %d: %w", u.ID, err)
+	}
+	return nil
+}
+
+func (s *Service) ResetPassword(ctx context.Context, raw, password string) error {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	_, err = s.Repo.ResetPasswordWithToken(ctx, auth.HashToken(raw), hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidToken
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go-next-cms/internal/i18n"
+	"go-next-cms/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const countryCacheTTL = time.Minute
+
+type countryCache struct {
+	mu       sync.RWMutex
+	byCode   map[string]models.Country
+	loadedAt time.Time
+}
+
+// Country looks a country up by code from a cache of the countries table that
+// is refreshed every countryCacheTTL. Unknown codes return pgx.ErrNoRows.
+func (s *Service) Country(ctx context.Context, code string) (*models.Country, error) {
+	code = strings.ToUpper(code)
+	s.countries.mu.RLock()
+	co, ok := s.countries.byCode[code]
+	fresh := time.Since(s.countries.loadedAt) < countryCacheTTL
+	s.countries.mu.RUnlock()
+	if !fresh {
+		list, err := s.Repo.Countries(ctx)
+		if err != nil {
+			return nil, err
+		}
+		byCode := make(map[string]models.Country, len(list))
+		for _, x := range list {
+			// Parsed once per refresh rather than on every request; the
+			// admin form rejects bad chains, so an error here means the
+			// column was edited by hand.
+			fb, err := i18n.ParseFallbacks(x.Fallbacks)
+			if err != nil {
+				log.Printf("country %s: ignoring fallbacks: %v", x.Code, err)
+			}
+			x.FallbackChains = fb
+			byCode[x.Code] = x
+		}
+		s.countries.mu.Lock()
+		s.countries.byCode, s.countries.loadedAt = byCode, time.Now()
+		s.countries.mu.Unlock()
+		co, ok = byCode[code]
+	}
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &co, nil
+}
+
+// ForgetCountries drops the cache so a newly created country is visible at
+// once.
+func (s *Service) ForgetCountries() {
+	s.countries.mu.Lock()
+	s.countries.loadedAt = time.Time{}
+	s.countries.mu.Unlock()
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go-next-cms/internal/models"
+)
+
+var ErrOutsideScope = errors.New("country outside your assignment")
+
+// GrantCountries replaces target's country grants with countryIDs, or with
+// every country when all is set. Only global staff may grant every country; a
+// country-scoped granter may only manage users inside their own scope, hand
+// out their own countries and not change their own grants.
+func (s *Service) GrantCountries(ctx context.Context, granter *models.User, targetID int64, all bool, countryIDs []int64) error {
+	target, err := s.Repo.UserByID(ctx, targetID)
+	if err != nil {
+		return err
+	}
+	if !granter.AllCountries {
+		if all || granter.ID == targetID || !granter.Manages(target) {
+			return ErrOutsideScope
+		}
+		for _, id := range countryIDs {
+			if !granter.InCountry(id) {
+				return ErrOutsideScope
+			}
+		}
+	}
+	if all {
+		countryIDs = nil
+	}
+	return s.Repo.SetUserCountries(ctx, targetID, all, countryIDs)
+}
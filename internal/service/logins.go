@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-next-cms/internal/auth"
+	"go-next-cms/internal/models"
+	"go-next-cms/internal/repo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const auditTrailSize = 200
+
+var (
+	// accountThrottle locks an account after 10 failures in a row and delays
+	// attempts progressively from the fourth.
+	accountThrottle = repo.LoginThrottle{LockAfter: 10, LockFor: 15 * time.Minute, DelayFree: 3, MaxDelay: 30 * time.Second}
+	// ipThrottle blocks an IP after 50 failures in 15 minutes and delays it
+	// progressively from the eleventh, leaving room for shared addresses.
+	ipThrottle = repo.LoginThrottle{LockAfter: 50, LockFor: 15 * time.Minute, DelayFree: 10, MaxDelay: 30 * time.Second}
+)
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// dummyHash keeps unknown-email logins roughly as slow as real ones.
+var dummyHash, _ = auth.HashPassword("not-a-real-password")
+
+// ThrottleError is returned when a login is refused without checking the
+// password, either because of a lockout or a progressive delay.
+type ThrottleError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottleError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("account temporarily locked, try again in %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("too many login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+type LoginRequest struct {
+	Email     string
+	Password  string
+	IP        string
+	UserAgent string
+}
+
+// Authenticate checks credentials behind per-IP and per-account throttling
+// and records every attempt in the login audit trail.
+func (s *Service) Authenticate(ctx context.Context, req LoginRequest) (*models.User, error) {
+	attempt := &models.LoginAttempt{Email: strings.ToLower(strings.TrimSpace(req.Email)), IP: req.IP, UserAgent: req.UserAgent}
+	gate, err := s.Repo.IPLoginGate(ctx, req.IP, ipThrottle)
+	if err != nil {
+		return nil, err
+	}
+	if !gate.Passed {
+		attempt.Reason = "ip_throttled"
+		if gate.Locked {
+			attempt.Reason = "ip_blocked"
+		}
+		return nil, s.refuse(ctx, attempt, &ThrottleError{RetryAfter: gate.RetryAfter})
+	}
+	u, err := s.Repo.UserByEmail(ctx, attempt.Email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		_ = auth.CheckPassword(dummyHash, req.Password)
+		attempt.Reason = "unknown_email"
+		return nil, s.refuse(ctx, attempt, ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, err
+	}
+	attempt.UserID = &u.ID
+	gate, err = s.Repo.TryLogin(ctx, u.ID, accountThrottle, func() (bool, error) {
+		return auth.CheckPassword(u.PasswordHash, req.Password) == nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refuseGate(ctx, attempt, gate, "bad_password", ErrInvalidCredentials); err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabledAt != nil {
+		// The attempt is only a success once the second factor checks out.
+		return u, nil
+	}
+	return u, s.LoginSucceeded(ctx, u, req.IP, req.UserAgent)
+}
+
+// SecondFactorLogin checks a TOTP or recovery code for u, who passed the
+// password step, under the same lockout and delay as passwords, and records
+// the login.
+func (s *Service) SecondFactorLogin(ctx context.Context, u *models.User, code, ip, userAgent string) error {
+	attempt := &models.LoginAttempt{UserID: &u.ID, Email: u.Email, IP: ip, UserAgent: userAgent}
+	gate, err := s.Repo.TryLogin(ctx, u.ID, accountThrottle, func() (bool, error) {
+		err := s.VerifySecondFactor(ctx, u, code)
+		if errors.Is(err, ErrInvalidCode) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.refuseGate(ctx, attempt, gate, "bad_second_factor", ErrInvalidCode); err != nil {
+		return err
+	}
+	return s.LoginSucceeded(ctx, u, ip, userAgent)
+}
+
+func (s *Service) LoginSucceeded(ctx context.Context, u *models.User, ip, userAgent string) error {
+	if err := s.Repo.ResetLoginFailures(ctx, u.ID); err != nil {
+		return err
+	}
+	return s.Repo.RecordLoginAttempt(ctx, &models.LoginAttempt{UserID: &u.ID, Email: u.Email, IP: ip, UserAgent: userAgent, Success: true})
+}
+
+func (s *Service) UnlockUser(ctx context.Context, id int64) error {
+	return s.Repo.ResetLoginFailures(ctx, id)
+}
+
+func (s *Service) LoginAudit(ctx context.Context) ([]models.LoginAttempt, error) {
+	return s.Repo.LoginAttempts(ctx, auditTrailSize)
+}
+
+// refuseGate records attempt and returns the error for an account gate that
+// did not pass: a lockout, a delay, or failed, the failed check.
+func (s *Service) refuseGate(ctx context.Context, a *models.LoginAttempt, gate repo.LoginGate, reason string, failed error) error {
+	switch {
+	case gate.Passed:
+		return nil
+	case gate.Locked:
+		a.Reason = "locked"
+		return s.refuse(ctx, a, &ThrottleError{RetryAfter: gate.RetryAfter, Locked: true})
+	case gate.RetryAfter > 0:
+		a.Reason = "throttled"
+		return s.refuse(ctx, a, &ThrottleError{RetryAfter: gate.RetryAfter})
+	}
+	a.Reason = reason
+	return s.refuse(ctx, a, failed)
+}
+
+func (s *Service) refuse(ctx context.Context, a *models.LoginAttempt, reason error) error {
+	if err := s.Repo.RecordLoginAttempt(ctx, a); err != nil {
+		return err
+	}
+	return reason
+}
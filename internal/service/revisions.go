@@ -0,0 +1,76 @@
+package service
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"go-next-cms/internal/models"
+)
+
+func DiffSnapshots(prev, next models.DealSnapshot) []models.FieldChange {
+	var out []models.FieldChange
+	add := func(field, a, b string) {
+		if a != b {
+			out = append(out, models.FieldChange{Field: field, Old: a, New: b})
+		}
+	}
+	add("title", prev.Title, next.Title)
+	add("description", prev.Description, next.Description)
+	add("city_id", idString(prev.CityID), idString(next.CityID))
+	add("category_id", idString(prev.CategoryID), idString(next.CategoryID))
+	add("merchant_id", optionalID(prev.MerchantID), optionalID(next.MerchantID))
+	add("deal_type_id", idString(prev.DealTypeID), idString(next.DealTypeID))
+	add("start_at", dateString(prev.StartAt), dateString(next.StartAt))
+	add("end_at", dateString(prev.EndAt), dateString(next.EndAt))
+	add("image_url", prev.ImageURL, next.ImageURL)
+
+	prevTr := translationsByLang(prev.Translations)
+	nextTr := translationsByLang(next.Translations)
+	langs := map[string]bool{}
+	for l := range prevTr {
+		langs[l] = true
+	}
+	for l := range nextTr {
+		langs[l] = true
+	}
+	sorted := make([]string, 0, len(langs))
+	for l := range langs {
+		sorted = append(sorted, l)
+	}
+	sort.Strings(sorted)
+	for _, l := range sorted {
+		add("title_"+l, prevTr[l].Title, nextTr[l].Title)
+		add("description_"+l, prevTr[l].Description, nextTr[l].Description)
+	}
+	return out
+}
+
+func translationsByLang(trs []models.SnapshotTranslation) map[string]models.SnapshotTranslation {
+	m := make(map[string]models.SnapshotTranslation, len(trs))
+	for _, t := range trs {
+		m[t.Lang] = t
+	}
+	return m
+}
+
+func idString(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+func optionalID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+	return idString(*id)
+}
+
+func dateString(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
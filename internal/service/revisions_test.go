@@ -0,0 +1,67 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go-next-cms/internal/models"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	merchant, other := int64(4), int64(9)
+	base := models.DealSnapshot{
+		Title: "Half price", Description: "Two for one", CityID: 1, CategoryID: 2, DealTypeID: 3,
+		StartAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), EndAt: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC),
+		Translations: []models.SnapshotTranslation{{Lang: "si", Title: "අඩක් මිලට", Description: "එකක් දෙකකට"}},
+	}
+	tests := []struct {
+		name   string
+		change func(s *models.DealSnapshot)
+		want   []models.FieldChange
+	}{
+		{"unchanged", func(s *models.DealSnapshot) {}, nil},
+		{"fields", func(s *models.DealSnapshot) {
+			s.Title, s.CityID, s.EndAt, s.ImageURL = "Full price", 7, s.EndAt.AddDate(0, 0, 1), "/uploads/a.jpg"
+		}, []models.FieldChange{
+			{Field: "title", Old: "Half price", New: "Full price"},
+			{Field: "city_id", Old: "1", New: "7"},
+			{Field: "end_at", Old: "2026-01-31", New: "2026-02-01"},
+			{Field: "image_url", Old: "", New: "/uploads/a.jpg"},
+		}},
+		{"merchant set", func(s *models.DealSnapshot) { s.MerchantID = &merchant }, []models.FieldChange{{Field: "merchant_id", Old: "", New: "4"}}},
+		{"translation added", func(s *models.DealSnapshot) {
+			s.Translations = append(s.Translations, models.SnapshotTranslation{Lang: "ta", Title: "பாதி விலை"})
+		}, []models.FieldChange{{Field: "title_ta", Old: "", New: "பாதி விலை"}}},
+		{"translation removed", func(s *models.DealSnapshot) { s.Translations = nil }, []models.FieldChange{
+			{Field: "title_si", Old: "අඩක් මිලට", New: ""},
+			{Field: "description_si", Old: "එකක් දෙකකට", New: ""},
+		}},
+		{"translation changed", func(s *models.DealSnapshot) {
+			s.Translations = []models.SnapshotTranslation{{Lang: "si", Title: "අඩක් මිලට", Description: "නොමිලේ"}}
+		}, []models.FieldChange{{Field: "description_si", Old: "එකක් දෙකකට", New: "නොමිලේ"}}},
+		{"review state only", func(s *models.DealSnapshot) {
+			s.Translations = []models.SnapshotTranslation{{Lang: "si", Title: "අඩක් මිලට", Description: "එකක් දෙකකට", Status: models.TranslationReviewed}}
+		}, nil},
+	}
+	for _, tt := range tests {
+		next := base
+		next.Translations = append([]models.SnapshotTranslation(nil), base.Translations...)
+		tt.change(&next)
+		if got := DiffSnapshots(base, next); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: DiffSnapshots = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	prev, next := base, base
+	prev.MerchantID, next.MerchantID = &merchant, &other
+	want := []models.FieldChange{{Field: "merchant_id", Old: "4", New: "9"}}
+	if got := DiffSnapshots(prev, next); !reflect.DeepEqual(got, want) {
+		t.Errorf("merchant changed: DiffSnapshots = %+v, want %+v", got, want)
+	}
+	next.MerchantID = nil
+	want = []models.FieldChange{{Field: "merchant_id", Old: "4", New: ""}}
+	if got := DiffSnapshots(prev, next); !reflect.DeepEqual(got, want) {
+		t.Errorf("merchant cleared: DiffSnapshots = %+v, want %+v", got, want)
+	}
+}
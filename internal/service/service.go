@@ -7,14 +7,25 @@ import (
 	"strings"
 	"time"
 
+	"go-next-cms/internal/mail"
 	"go-next-cms/internal/models"
 	"go-next-cms/internal/repo"
 	"go-next-cms/internal/util"
 )
 
-type Service struct{ Repo *repo.Repository }
+type Service struct {
+	Repo    *repo.Repository
+	Mailer  mail.Mailer
+	BaseURL string
+	// TOTPKey seals TOTP secrets at rest; see auth.SealSecret.
+	TOTPKey []byte
 
-func New(r *repo.Repository) *Service { return &Service{Repo: r} }
+	countries countryCache
+}
+
+func New(r *repo.Repository, m mail.Mailer, baseURL string) *Service {
+	return &Service{Repo: r, Mailer: m, BaseURL: strings.TrimRight(baseURL, "/")}
+}
 
 func (s *Service) UniqueSlug(ctx context.Context, countryID int64, title string) (string, error) {
 	base := util.Slugify(title)
@@ -36,15 +47,15 @@ func ParseDate(s string) (time.Time, error) {
 	return time.Parse("2006-01-02", strings.TrimSpace(s))
 }
 
-func AllowedLang(lang string) string {
-	if lang == "si" || lang == "ta" || lang == "en" {
-		return lang
-	}
-	return "en"
+// endOfDay is the last instant Postgres can store on day's date. Deals keep
+// it as end_at, so a deal is listed through its whole final day and one that
+// starts and ends on the same date is listed at all.
+func endOfDay(day time.Time) time.Time {
+	return day.AddDate(0, 0, 1).Add(-time.Microsecond)
 }
 
-func DealEditable(d *models.Deal, userID int64, isAdmin bool) bool {
-	if isAdmin {
+func DealEditable(d *models.Deal, userID int64, canModerate bool) bool {
+	if canModerate {
 		return true
 	}
 	if d.CreatedByUserID != userID {
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go-next-cms/internal/models"
+)
+
+var ErrOwnTranslation = errors.New("translation needs review by someone other than its author")
+
+// ReviewTranslation marks t, a translation of a deal in countryID, reviewed
+// by u. Moderators there may review any text, their own edits included;
+// other translators can only confirm stored text someone else wrote.
+func (s *Service) ReviewTranslation(ctx context.Context, u *models.User, countryID int64, t models.DealTranslation) error {
+	if u.CanIn(models.PermModerate, countryID) {
+		return s.Repo.ReviewTranslation(ctx, t, u.ID)
+	}
+	ok, err := s.Repo.ConfirmTranslation(ctx, t, u.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOwnTranslation
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-next-cms/internal/auth"
+	"go-next-cms/internal/models"
+)
+
+const (
+	totpIssuer        = "Deals"
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrInvalidCode = errors.New("invalid authentication code")
+	ErrNoTOTPKey   = errors.New("TOTP_KEY is not set")
+)
+
+func TOTPProvisioning(u *models.User) (secret, uri string, err error) {
+	secret, err = auth.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	return secret, auth.TOTPProvisioningURI(totpIssuer, u.Email, secret), nil
+}
+
+// EnableTOTP confirms enrollment of secret with a current code and returns
+// freshly generated recovery codes, which are only stored hashed.
+func (s *Service) EnableTOTP(ctx context.Context, u *models.User, secret, code string) ([]string, error) {
+	if _, ok := auth.ValidateTOTP(secret, code, time.Now()); !ok {
+		return nil, ErrInvalidCode
+	}
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, auth.HashToken(auth.NormalizeRecoveryCode(c)))
+	}
+	sealed, err := s.sealTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.EnableTOTP(ctx, u.ID, sealed, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifySecondFactor accepts either a TOTP code, which may only be used once,
+// or an unused recovery code.
+func (s *Service) VerifySecondFactor(ctx context.Context, u *models.User, code string) error {
+	if u.TOTPEnabledAt == nil {
+		return ErrInvalidCode
+	}
+	secret, legacy, err := auth.OpenSecret(s.TOTPKey, u.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	if step, ok := auth.ValidateTOTP(secret, code, time.Now()); ok {
+		fresh, err := s.Repo.UseTOTPStep(ctx, u.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidCode
+		}
+		if legacy && s.TOTPKey != nil {
+			// Secrets enrolled before sealing are sealed on their next use.
+			sealed, err := s.sealTOTPSecret(secret)
+			if err != nil {
+				return err
+			}
+			return s.Repo.SetTOTPSecret(ctx, u.ID, sealed)
+		}
+		return nil
+	}
+	used, err := s.Repo.UseRecoveryCode(ctx, u.ID, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *Service) DisableTOTP(ctx context.Context, u *models.User, code string) error {
+	if err := s.VerifySecondFactor(ctx, u, code); err != nil {
+		return err
+	}
+	return s.Repo.DisableTOTP(ctx, u.ID)
+}
+
+func (s *Service) sealTOTPSecret(secret string) (string, error) {
+	if s.TOTPKey == nil {
+		return "", ErrNoTOTPKey
+	}
+	return auth.SealSecret(s.TOTPKey, secret)
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-next-cms/internal/auth"
+	"go-next-cms/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	MinPasswordLen = 8
+	MinTitleLen    = 3
+	MaxTitleLen    = 120
+)
+
+var slugRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// FieldError is a problem with one form field, described by the i18n message
+// Key with Args.
+type FieldError struct {
+	Field string
+	Key   string
+	Args  []any
+}
+
+// ValidationError holds the field errors of a rejected form.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Key)
+	}
+	return "invalid input: " + strings.Join(parts, ", ")
+}
+
+// For returns the errors of field.
+func (e *ValidationError) For(field string) []FieldError {
+	if e == nil {
+		return nil
+	}
+	var out []FieldError
+	for _, f := range e.Fields {
+		if f.Field == field {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (e *ValidationError) Add(field, key string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Key: key, Args: args})
+}
+
+// Has reports whether field already has an error, so later checks on it can
+// be skipped.
+func (e *ValidationError) Has(field string) bool { return len(e.For(field)) > 0 }
+
+// Err returns e, or nil when there are no field errors.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) required(field, value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		e.Add(field, "error_required")
+	}
+	return value
+}
+
+func (e *ValidationError) id(field, value string) int64 {
+	if e.required(field, value) == "" {
+		return 0
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || id <= 0 {
+		e.Add(field, "error_invalid_choice")
+	}
+	return id
+}
+
+func (e *ValidationError) date(field, value string) time.Time {
+	if e.required(field, value) == "" {
+		return time.Time{}
+	}
+	d, err := ParseDate(value)
+	if err != nil {
+		e.Add(field, "error_invalid_date")
+	}
+	return d
+}
+
+func (e *ValidationError) slug(field, value string) string {
+	value = e.required(field, value)
+	if value != "" && !slugRe.MatchString(value) {
+		e.Add(field, "error_invalid_slug")
+	}
+	return value
+}
+
+// uniqueViolation turns a unique constraint failure into a field error on
+// field, and returns other errors as they are.
+func uniqueViolation(err error, field string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		v := &ValidationError{}
+		v.Add(field, "error_taken")
+		return v
+	}
+	return err
+}
+
+// DealInput is a deal form as submitted.
+type DealInput struct {
+	Title       string
+	Description string
+	CountryID   string
+	CityID      string
+	CategoryID  string
+	DealTypeID  string
+	StartAt     string
+	EndAt       string
+}
+
+// ApplyDealText validates the title and dates of in and copies them with the
+// description onto d. The end date is inclusive and stored as the end of
+// that day.
+func ApplyDealText(v *ValidationError, in DealInput, d *models.Deal) {
+	d.Title = v.required("title", in.Title)
+	if n := len([]rune(d.Title)); d.Title != "" && (n < MinTitleLen || n > MaxTitleLen) {
+		v.Add("title", "error_title_length", "min", MinTitleLen, "max", MaxTitleLen)
+	}
+	d.Description = strings.TrimSpace(in.Description)
+	d.StartAt = v.date("start_at", in.StartAt)
+	d.EndAt = v.date("end_at", in.EndAt)
+	if !v.Has("start_at") && !v.Has("end_at") && d.EndAt.Before(d.StartAt) {
+		v.Add("end_at", "error_end_before_start")
+	}
+	if !v.Has("end_at") {
+		d.EndAt = endOfDay(d.EndAt)
+	}
+}
+
+// ValidateDeal checks a new deal form and returns the deal it describes: all
+// fields required, the title length, the end not before the start, and a city,
+// category and deal type that exist, the city in the chosen country.
+func (s *Service) ValidateDeal(ctx context.Context, in DealInput) (*models.Deal, error) {
+	v := &ValidationError{}
+	d := &models.Deal{}
+	ApplyDealText(v, in, d)
+	d.CityID = v.id("city_id", in.CityID)
+	d.CategoryID = v.id("category_id", in.CategoryID)
+	d.DealTypeID = v.id("deal_type_id", in.DealTypeID)
+	if !v.Has("city_id") {
+		city, err := s.Repo.CityByID(ctx, d.CityID)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			v.Add("city_id", "error_unknown_city")
+		case err != nil:
+			return nil, err
+		case strings.TrimSpace(in.CountryID) != "" && strings.TrimSpace(in.CountryID) != strconv.FormatInt(city.CountryID, 10):
+			v.Add("city_id", "error_city_country")
+		default:
+			d.CountryID = city.CountryID
+		}
+	}
+	for _, ref := range []struct {
+		field  string
+		entity models.MasterEntity
+		id     int64
+	}{{"category_id", models.EntityCategory, d.CategoryID}, {"deal_type_id", models.EntityDealType, d.DealTypeID}} {
+		if v.Has(ref.field) {
+			continue
+		}
+		ok, err := s.Repo.MasterExists(ctx, ref.entity, ref.id)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			v.Add(ref.field, "error_invalid_choice")
+		}
+	}
+	return d, v.Err()
+}
+
+// RegisterInput is a registration form as submitted.
+type RegisterInput struct {
+	Name     string
+	Email    string
+	Password string
+}
+
+// Register validates in and creates a submitter account for it.
+func (s *Service) Register(ctx context.Context, in RegisterInput) (*models.User, error) {
+	v := &ValidationError{}
+	u := &models.User{Role: models.RoleSubmitter}
+	u.Name = v.required("name", in.Name)
+	u.Email = strings.ToLower(v.required("email", in.Email))
+	if u.Email != "" && !validEmail(u.Email) {
+		v.Add("email", "error_invalid_email")
+	}
+	if len(in.Password) < MinPasswordLen {
+		v.Add("password", "error_password_length", "min", MinPasswordLen)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	hash, err := auth.HashPassword(in.Password)
+	if err != nil {
+		return nil, err
+	}
+	u.PasswordHash = hash
+	if err := s.Repo.CreateUser(ctx, u); err != nil {
+		return nil, uniqueViolation(err, "email")
+	}
+	return u, nil
+}
+
+// CreateCountry validates and creates a country; languages are the codes it
+// may use. The default language must be among the country's languages, or
+// among languages when it lists none.
+func (s *Service) CreateCountry(ctx context.Context, co *models.Country, languages []string) error {
+	v := &ValidationError{}
+	co.Code = strings.ToUpper(v.required("code", co.Code))
+	if co.Code != "" && !ValidCountryCode(co.Code) {
+		v.Add("code", "error_invalid_country")
+	}
+	co.Name = v.required("name", co.Name)
+	for _, l := range co.Languages {
+		if !contains(languages, l) {
+			v.Add("languages", "error_invalid_choice")
+			break
+		}
+	}
+	chosen := co.Languages
+	if len(chosen) == 0 {
+		chosen = languages
+	}
+	if !contains(chosen, co.DefaultLanguage) {
+		v.Add("default_language", "error_invalid_choice")
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	if err := s.Repo.CreateCountry(ctx, co); err != nil {
+		return uniqueViolation(err, "code")
+	}
+	s.ForgetCountries()
+	return nil
+}
+
+// CreateCity validates and creates a city in an existing country.
+func (s *Service) CreateCity(ctx context.Context, c *models.City) error {
+	v := &ValidationError{}
+	if c.CountryID <= 0 {
+		v.Add("country_id", "error_invalid_country")
+	} else if _, err := s.Repo.CountryByID(ctx, c.CountryID); errors.Is(err, pgx.ErrNoRows) {
+		v.Add("country_id", "error_invalid_country")
+	} else if err != nil {
+		return err
+	}
+	c.Name = v.required("name", c.Name)
+	c.Slug = v.slug("slug", c.Slug)
+	if err := v.Err(); err != nil {
+		return err
+	}
+	return uniqueViolation(s.Repo.CreateCity(ctx, c), "slug")
+}
+
+func (s *Service) CreateCategory(ctx context.Context, c *models.Category) error {
+	v := &ValidationError{}
+	c.Name = v.required("name", c.Name)
+	c.Slug = v.slug("slug", c.Slug)
+	if err := v.Err(); err != nil {
+		return err
+	}
+	return uniqueViolation(s.Repo.CreateCategory(ctx, c), "slug")
+}
+
+func (s *Service) CreateMerchant(ctx context.Context, m *models.Merchant) error {
+	v := &ValidationError{}
+	m.Name = v.required("name", m.Name)
+	m.Slug = v.slug("slug", m.Slug)
+	m.Contact = strings.TrimSpace(m.Contact)
+	if err := v.Err(); err != nil {
+		return err
+	}
+	return uniqueViolation(s.Repo.CreateMerchant(ctx, m), "slug")
+}
+
+func (s *Service) CreateDealType(ctx context.Context, dt *models.DealType) error {
+	v := &ValidationError{}
+	dt.Code = v.slug("code", dt.Code)
+	dt.Name = v.required("name", dt.Name)
+	if err := v.Err(); err != nil {
+		return err
+	}
+	return uniqueViolation(s.Repo.CreateDealType(ctx, dt), "code")
+}
+
+// ValidCountryCode reports whether cc is two uppercase ASCII letters.
+func ValidCountryCode(cc string) bool {
+	if len(cc) != 2 {
+		return false
+	}
+	for _, r := range cc {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// validEmail reports whether s is a bare address, without a display name or
+// angle brackets.
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-next-cms/internal/models"
+)
+
+func TestValidationError(t *testing.T) {
+	var none *ValidationError
+	if got := none.For("title"); got != nil {
+		t.Errorf("nil For = %v, want nil", got)
+	}
+	v := &ValidationError{}
+	if v.Err() != nil {
+		t.Error("empty Err() != nil")
+	}
+	v.Add("title", "error_required")
+	v.Add("end_at", "error_end_before_start")
+	v.Add("title", "error_title_length", "min", 3)
+	if !v.Has("title") || v.Has("description") {
+		t.Errorf("Has: title %v, description %v; want true, false", v.Has("title"), v.Has("description"))
+	}
+	want := []FieldError{{"title", "error_required", nil}, {"title", "error_title_length", []any{"min", 3}}}
+	if got := v.For("title"); !reflect.DeepEqual(got, want) {
+		t.Errorf("For(title) = %v, want %v", got, want)
+	}
+	if v.Err() == nil {
+		t.Error("Err() = nil with errors")
+	}
+	if got, want := v.Error(), "invalid input: title: error_required, end_at: error_end_before_start, title: error_title_length"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestApplyDealText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   DealInput
+		errs map[string]string
+	}{
+		{"valid", DealInput{Title: " Half price ", StartAt: "2026-01-01", EndAt: "2026-01-31"}, nil},
+		{"same day", DealInput{Title: "One day", StartAt: "2026-01-01", EndAt: "2026-01-01"}, nil},
+		{"end before start", DealInput{Title: "Backwards", StartAt: "2026-01-02", EndAt: "2026-01-01"}, map[string]string{"end_at": "error_end_before_start"}},
+		{"missing", DealInput{}, map[string]string{"title": "error_required", "start_at": "error_required", "end_at": "error_required"}},
+		{"short title", DealInput{Title: "ab", StartAt: "2026-01-01", EndAt: "2026-01-02"}, map[string]string{"title": "error_title_length"}},
+		{"long title", DealInput{Title: strings.Repeat("a", MaxTitleLen+1), StartAt: "2026-01-01", EndAt: "2026-01-02"}, map[string]string{"title": "error_title_length"}},
+		{"counted in runes", DealInput{Title: "ඩීල්", StartAt: "2026-01-01", EndAt: "2026-01-02"}, nil},
+		{"bad date", DealInput{Title: "Dates", StartAt: "01/02/2026", EndAt: "2026-01-02"}, map[string]string{"start_at": "error_invalid_date"}},
+	}
+	for _, tt := range tests {
+		v := &ValidationError{}
+		d := &models.Deal{}
+		ApplyDealText(v, tt.in, d)
+		got := map[string]string{}
+		for _, f := range v.Fields {
+			got[f.Field] = f.Key
+		}
+		if len(got) == 0 {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, tt.errs) {
+			t.Errorf("%s: errors %v, want %v", tt.name, got, tt.errs)
+		}
+	}
+
+	v := &ValidationError{}
+	d := &models.Deal{}
+	ApplyDealText(v, DealInput{Title: " Half price ", Description: " Two for one \n", StartAt: "2026-01-01", EndAt: "2026-01-31"}, d)
+	if d.Title != "Half price" || d.Description != "Two for one" {
+		t.Errorf("text = %q, %q; want trimmed", d.Title, d.Description)
+	}
+	if want := time.Date(2026, 1, 31, 23, 59, 59, 999999000, time.UTC); !d.EndAt.Equal(want) {
+		t.Errorf("EndAt = %v, want %v", d.EndAt, want)
+	}
+}
+
+// TestApplyDealTextOneDay checks a deal starting and ending on one date
+// against the listing condition of the deal queries, start_at <= NOW() AND
+// end_at > NOW(), through the whole of that day.
+func TestApplyDealTextOneDay(t *testing.T) {
+	v := &ValidationError{}
+	d := &models.Deal{}
+	ApplyDealText(v, DealInput{Title: "One day", StartAt: "2026-01-01", EndAt: "2026-01-01"}, d)
+	if err := v.Err(); err != nil {
+		t.Fatal(err)
+	}
+	listed := func(now time.Time) bool { return !d.StartAt.After(now) && d.EndAt.After(now) }
+	for _, tt := range []struct {
+		now  time.Time
+		want bool
+	}{
+		{time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC), false},
+		{time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 1, 1, 23, 59, 59, 0, time.UTC), true},
+		{time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), false},
+	} {
+		if got := listed(tt.now); got != tt.want {
+			t.Errorf("listed at %v = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+	if got := d.EndAt.Format(time.DateOnly); got != "2026-01-01" {
+		t.Errorf("end date shows as %s, want 2026-01-01", got)
+	}
+}
+
+func TestValidCountryCode(t *testing.T) {
+	for cc, want := range map[string]bool{"LK": true, "IN": true, "lk": false, "L": false, "LKA": false, "L1": false, "": false} {
+		if got := ValidCountryCode(cc); got != want {
+			t.Errorf("ValidCountryCode(%q) = %v, want %v", cc, got, want)
+		}
+	}
+}
+
+func TestSlugRe(t *testing.T) {
+	for slug, want := range map[string]bool{"colombo": true, "new-york": true, "deal-2026": true, "a": true,
+		"Colombo": false, "-colombo": false, "colombo-": false, "new--york": false, "new york": false, "": false, "කොළඹ": false} {
+		if got := slugRe.MatchString(slug); got != want {
+			t.Errorf("slugRe.MatchString(%q) = %v, want %v", slug, got, want)
+		}
+	}
+}
+
+func TestValidEmail(t *testing.T) {
+	for email, want := range map[string]bool{"ana@example.com": true, "ana+deals@mail.example.lk": true,
+		"ana": false, "ana@": false, "@example.com": false, "Ana <ana@example.com>": false, "<ana@example.com>": false, "ana@example.com, bob@example.com": false} {
+		if got := validEmail(email); got != want {
+			t.Errorf("validEmail(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go-next-cms/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Actor string
+
+const (
+	ActorSubmitter Actor = "submitter"
+	ActorAdmin     Actor = "admin"
+	ActorSystem    Actor = "system"
+)
+
+var (
+	ErrInvalidTransition    = errors.New("invalid deal status transition")
+	ErrTransitionNotAllowed = errors.New("deal status transition not allowed")
+)
+
+type TransitionError struct {
+	From  models.DealStatus
+	To    models.DealStatus
+	Actor Actor
+	Err   error
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s by %s", e.Err, e.From, e.To, e.Actor)
+}
+
+func (e *TransitionError) Unwrap() error { return e.Err }
+
+type transition struct {
+	From   models.DealStatus
+	To     models.DealStatus
+	Actors []Actor
+	Effect func(ch *models.DealStatusChange)
+}
+
+// dealTransitions is the complete deal workflow; any status change not listed
+// here is rejected with ErrInvalidTransition.
+var dealTransitions = []transition{
+	{From: models.DealDraft, To: models.DealPending, Actors: []Actor{ActorSubmitter, ActorAdmin}},
+	{From: models.DealPending, To: models.DealApproved, Actors: []Actor{ActorAdmin}, Effect: func(ch *models.DealStatusChange) {
+		ch.StampApproved = true
+		ch.ClearRejection = true
+	}},
+	{From: models.DealPending, To: models.DealRejected, Actors: []Actor{ActorAdmin}},
+	{From: models.DealRejected, To: models.DealPending, Actors: []Actor{ActorSubmitter, ActorAdmin}, Effect: func(ch *models.DealStatusChange) {
+		ch.ClearRejection = true
+	}},
+	{From: models.DealApproved, To: models.DealPublished, Actors: []Actor{ActorAdmin, ActorSystem}, Effect: func(ch *models.DealStatusChange) {
+		ch.StampPublished = true
+	}},
+	{From: models.DealPending, To: models.DealExpired, Actors: []Actor{ActorSystem}},
+	{From: models.DealRejected, To: models.DealExpired, Actors: []Actor{ActorSystem}},
+	{From: models.DealApproved, To: models.DealExpired, Actors: []Actor{ActorSystem}},
+	{From: models.DealPublished, To: models.DealExpired, Actors: []Actor{ActorSystem}},
+}
+
+func findTransition(from, to models.DealStatus, actor Actor) (transition, error) {
+	for _, t := range dealTransitions {
+		if t.From != from || t.To != to {
+			continue
+		}
+		if !slices.Contains(t.Actors, actor) {
+			return t, &TransitionError{From: from, To: to, Actor: actor, Err: ErrTransitionNotAllowed}
+		}
+		return t, nil
+	}
+	return transition{}, &TransitionError{From: from, To: to, Actor: actor, Err: ErrInvalidTransition}
+}
+
+// planTransition walks steps from the current status and folds the side
+// effects of every step into a single change.
+func planTransition(from models.DealStatus, actor Actor, reason *string, steps ...models.DealStatus) (models.DealStatusChange, error) {
+	ch := models.DealStatusChange{To: from}
+	for _, to := range steps {
+		if to == ch.To {
+			continue
+		}
+		t, err := findTransition(ch.To, to, actor)
+		if err != nil {
+			return ch, err
+		}
+		if t.Effect != nil {
+			t.Effect(&ch)
+		}
+		if to == models.DealRejected {
+			ch.RejectionReason = reason
+		}
+		ch.To = to
+		ch.Path = append(ch.Path, to)
+	}
+	return ch, nil
+}
+
+func ActorFor(u *models.User) Actor {
+	if u.Can(models.PermModerate) {
+		return ActorAdmin
+	}
+	return ActorSubmitter
+}
+
+// actorOf is the workflow actor for by, the system when by is nil, and the
+// user ID recorded in the status history.
+func actorOf(by *models.User) (Actor, *int64) {
+	if by == nil {
+		return ActorSystem, nil
+	}
+	return ActorFor(by), &by.ID
+}
+
+// TransitionDeal moves deal id through steps as by, or as the system when by
+// is nil, and records each step in the deal's status history.
+func (s *Service) TransitionDeal(ctx context.Context, id int64, by *models.User, reason *string, steps ...models.DealStatus) error {
+	actor, userID := actorOf(by)
+	return s.Repo.ChangeDealStatus(ctx, id, func(from models.DealStatus) (models.DealStatusChange, error) {
+		ch, err := planTransition(from, actor, reason, steps...)
+		ch.Actor, ch.UserID = string(actor), userID
+		return ch, err
+	})
+}
+
+// ExpireDeals moves pending, rejected, approved and published deals past
+// their end to expired, so ended deals also leave the moderation queue.
+func (s *Service) ExpireDeals(ctx context.Context) error {
+	ids, err := s.Repo.EndedDealIDs(ctx)
+	if err != nil {
+		return err
+	}
+	return s.systemTransitions(ctx, ids, models.DealExpired)
+}
+
+// PublishScheduledDeals publishes approved deals whose start has arrived.
+func (s *Service) PublishScheduledDeals(ctx context.Context) error {
+	ids, err := s.Repo.ScheduledDealIDs(ctx)
+	if err != nil {
+		return err
+	}
+	return s.systemTransitions(ctx, ids, models.DealPublished)
+}
+
+// systemTransitions moves each deal in ids to status to as the system. A
+// deal whose status changed, or that was deleted, since it was listed is
+// skipped.
+func (s *Service) systemTransitions(ctx context.Context, ids []int64, to models.DealStatus) error {
+	var errs []error
+	for _, id := range ids {
+		err := s.TransitionDeal(ctx, id, nil, nil, to)
+		if err != nil && !errors.Is(err, ErrInvalidTransition) && !errors.Is(err, pgx.ErrNoRows) {
+			errs = append(errs, fmt.Errorf("deal %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SaveSubmission persists edits to d. A submitter saving a draft or rejected
+// deal (re)submits it for moderation; admin edits leave the status alone.
+func (s *Service) SaveSubmission(ctx context.Context, d *models.Deal, translations []models.DealTranslation, editor *models.User) error {
+	return s.Repo.UpdateSubmission(ctx, d, translations, editor.ID, editDecision(editor))
+}
+
+// RestoreRevision writes revision revID back onto deal dealID as an edit by
+// editor, with the same status effect as SaveSubmission.
+func (s *Service) RestoreRevision(ctx context.Context, dealID, revID int64, editor *models.User) error {
+	return s.Repo.RestoreDealRevision(ctx, dealID, revID, editor.ID, editDecision(editor))
+}
+
+// editDecision is the status change for a content edit by editor.
+func editDecision(editor *models.User) func(from models.DealStatus) (models.DealStatusChange, error) {
+	actor, userID := actorOf(editor)
+	return func(from models.DealStatus) (models.DealStatusChange, error) {
+		if actor == ActorAdmin {
+			return models.DealStatusChange{To: from}, nil
+		}
+		ch, err := planTransition(from, actor, nil, models.DealPending)
+		ch.Actor, ch.UserID = string(actor), userID
+		return ch, err
+	}
+}
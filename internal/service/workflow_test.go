@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"go-next-cms/internal/models"
+)
+
+var (
+	allStatuses = []models.DealStatus{models.DealDraft, models.DealPending, models.DealApproved, models.DealPublished, models.DealRejected, models.DealExpired}
+	allActors   = []Actor{ActorSubmitter, ActorAdmin, ActorSystem}
+)
+
+func TestFindTransition(t *testing.T) {
+	type effects struct{ clearRejection, stampApproved, stampPublished bool }
+	allowed := map[[2]models.DealStatus]struct {
+		actors []Actor
+		effects
+	}{
+		{models.DealDraft, models.DealPending}:      {[]Actor{ActorSubmitter, ActorAdmin}, effects{}},
+		{models.DealPending, models.DealApproved}:   {[]Actor{ActorAdmin}, effects{clearRejection: true, stampApproved: true}},
+		{models.DealPending, models.DealRejected}:   {[]Actor{ActorAdmin}, effects{}},
+		{models.DealPending, models.DealExpired}:    {[]Actor{ActorSystem}, effects{}},
+		{models.DealRejected, models.DealPending}:   {[]Actor{ActorSubmitter, ActorAdmin}, effects{clearRejection: true}},
+		{models.DealRejected, models.DealExpired}:   {[]Actor{ActorSystem}, effects{}},
+		{models.DealApproved, models.DealPublished}: {[]Actor{ActorAdmin, ActorSystem}, effects{stampPublished: true}},
+		{models.DealApproved, models.DealExpired}:   {[]Actor{ActorSystem}, effects{}},
+		{models.DealPublished, models.DealExpired}:  {[]Actor{ActorSystem}, effects{}},
+	}
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			if from == to {
+				continue
+			}
+			for _, actor := range allActors {
+				want, listed := allowed[[2]models.DealStatus{from, to}]
+				ch, err := planTransition(from, actor, nil, to)
+				switch {
+				case !listed:
+					if !errors.Is(err, ErrInvalidTransition) {
+						t.Errorf("%s -> %s by %s: error %v, want ErrInvalidTransition", from, to, actor, err)
+					}
+				case !slices.Contains(want.actors, actor):
+					if !errors.Is(err, ErrTransitionNotAllowed) {
+						t.Errorf("%s -> %s by %s: error %v, want ErrTransitionNotAllowed", from, to, actor, err)
+					}
+				case err != nil:
+					t.Errorf("%s -> %s by %s: %v", from, to, actor, err)
+				default:
+					got := effects{ch.ClearRejection, ch.StampApproved, ch.StampPublished}
+					if got != want.effects || ch.To != to || !reflect.DeepEqual(ch.Path, []models.DealStatus{to}) {
+						t.Errorf("%s -> %s by %s: change %+v, want effects %+v", from, to, actor, ch, want.effects)
+					}
+				}
+				var te *TransitionError
+				if err != nil && (!errors.As(err, &te) || te.From != from || te.To != to || te.Actor != actor) {
+					t.Errorf("%s -> %s by %s: error %v is not a matching TransitionError", from, to, actor, err)
+				}
+			}
+		}
+	}
+}
+
+func TestPlanTransition(t *testing.T) {
+	reason := "blurry image"
+	ch, err := planTransition(models.DealPending, ActorAdmin, nil, models.DealApproved, models.DealPublished)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.DealStatusChange{To: models.DealPublished, Path: []models.DealStatus{models.DealApproved, models.DealPublished}, ClearRejection: true, StampApproved: true, StampPublished: true}
+	if !reflect.DeepEqual(ch, want) {
+		t.Errorf("approve and publish = %+v, want %+v", ch, want)
+	}
+
+	ch, err = planTransition(models.DealPending, ActorAdmin, &reason, models.DealRejected)
+	if err != nil || ch.RejectionReason != &reason {
+		t.Errorf("reject = %+v, %v; want the reason kept", ch, err)
+	}
+
+	ch, err = planTransition(models.DealPending, ActorSubmitter, nil, models.DealPending)
+	if err != nil || ch.To != models.DealPending || len(ch.Path) != 0 {
+		t.Errorf("same status = %+v, %v; want no steps", ch, err)
+	}
+
+	if _, err := planTransition(models.DealPending, ActorSubmitter, nil, models.DealApproved, models.DealPublished); !errors.Is(err, ErrTransitionNotAllowed) {
+		t.Errorf("submitter approving: error %v, want ErrTransitionNotAllowed", err)
+	}
+	if _, err := planTransition(models.DealExpired, ActorSystem, nil, models.DealPublished); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("publishing an expired deal: error %v, want ErrInvalidTransition", err)
+	}
+}
+
+func TestActorFor(t *testing.T) {
+	for role, want := range map[models.UserRole]Actor{models.RoleSubmitter: ActorSubmitter, models.RoleTranslator: ActorSubmitter, models.RoleModerator: ActorAdmin, models.RoleAdmin: ActorAdmin} {
+		if got := ActorFor(&models.User{Role: role}); got != want {
+			t.Errorf("ActorFor(%s) = %s, want %s", role, got, want)
+		}
+	}
+	if actor, id := actorOf(nil); actor != ActorSystem || id != nil {
+		t.Errorf("actorOf(nil) = %s, %v; want system, nil", actor, id)
+	}
+}
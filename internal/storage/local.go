@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"mime/multipart"
 	"os"
 	"path/filepath"
@@ -39,6 +41,32 @@ func (u LocalUploader) Save(c *fiber.Ctx, field string) (string, error) {
 	return filepath.ToSlash(filepath.Join(u.BasePath, name)), nil
 }
 
+// Prune deletes uploads last modified before cutoff whose public path is not
+// in keep.
+func (u LocalUploader) Prune(keep map[string]bool, cutoff time.Time) error {
+	entries, err := os.ReadDir(u.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || keep[filepath.ToSlash(filepath.Join(u.BasePath, e.Name()))] {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(u.Dir, e.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func isAllowed(name string) bool {
 	ext := strings.ToLower(filepath.Ext(name))
 	return ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".webp"
@@ -0,0 +1,62 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTP calls a self-hosted translation server speaking the LibreTranslate
+// API: POST {q, source, target, format, api_key} to URL, answered with
+// {"translatedText": ...} or {"error": ...}.
+type HTTP struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+type httpRequest struct {
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type httpResponse struct {
+	TranslatedText string `json:"translatedText"`
+	Error          string `json:"error"`
+}
+
+func (p *HTTP) Translate(ctx context.Context, text, from, to string) (string, error) {
+	body, err := json.Marshal(httpRequest{Q: text, Source: from, Target: to, Format: "text", APIKey: p.APIKey})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out httpResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return "", fmt.Errorf("translate %s->%s: %s: %w", from, to, resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Error != "" {
+		return "", fmt.Errorf("translate %s->%s: %s: %s", from, to, resp.Status, out.Error)
+	}
+	return out.TranslatedText, nil
+}
@@ -0,0 +1,46 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req httpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error": "bad json"}`, http.StatusBadRequest)
+			return
+		}
+		switch {
+		case req.APIKey != "key":
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(httpResponse{Error: "invalid API key"})
+		case req.Q == "broken":
+			w.Write([]byte("<html>oops</html>"))
+		case req.Q == "refused":
+			json.NewEncoder(w).Encode(httpResponse{Error: "language not supported"})
+		default:
+			json.NewEncoder(w).Encode(httpResponse{TranslatedText: req.Source + ">" + req.Target + ":" + req.Q})
+		}
+	}))
+	defer srv.Close()
+
+	p := &HTTP{URL: srv.URL, APIKey: "key"}
+	got, err := p.Translate(context.Background(), "pizza", "en", "si")
+	if err != nil || got != "en>si:pizza" {
+		t.Errorf("Translate = %q, %v; want en>si:pizza", got, err)
+	}
+	for _, q := range []string{"broken", "refused"} {
+		if _, err := p.Translate(context.Background(), q, "en", "si"); err == nil {
+			t.Errorf("Translate(%q): want error", q)
+		}
+	}
+	bad := &HTTP{URL: srv.URL, APIKey: "wrong"}
+	if _, err := bad.Translate(context.Background(), "pizza", "en", "si"); err == nil {
+		t.Error("Translate with a bad key: want error")
+	}
+}
@@ -0,0 +1,89 @@
+// Package translate drafts machine translations of deal text. Drafts are
+// stored flagged as machine-generated until a moderator reviews them.
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-next-cms/internal/config"
+)
+
+// Provider translates text between ISO 639 language codes.
+type Provider interface {
+	Translate(ctx context.Context, text, from, to string) (string, error)
+}
+
+// ErrNoTranslation is returned by Dictionary for text it knows no word of.
+var ErrNoTranslation = errors.New("no translation")
+
+// Dictionary is an offline provider for development and tests. Entries maps
+// a target language to source phrases and their translations. A text found
+// whole is replaced whole; otherwise each known word is replaced and the
+// rest is kept as is. Text with no known word is an ErrNoTranslation.
+type Dictionary struct {
+	Entries map[string]map[string]string
+}
+
+func (d Dictionary) Translate(_ context.Context, text, _, to string) (string, error) {
+	entries, ok := d.Entries[to]
+	if !ok {
+		return "", fmt.Errorf("dictionary: no entries for %q", to)
+	}
+	if out, ok := entries[strings.ToLower(strings.TrimSpace(text))]; ok {
+		return out, nil
+	}
+	words := strings.Fields(text)
+	found := false
+	for i, w := range words {
+		if out, ok := entries[strings.ToLower(w)]; ok {
+			words[i], found = out, true
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("dictionary: %w into %q", ErrNoTranslation, to)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// LoadDictionary reads a Dictionary from a JSON file shaped like
+// {"si": {"discount": "වට්ටම්"}}. Source phrases are matched lowercase.
+func LoadDictionary(path string) (Dictionary, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Dictionary{}, err
+	}
+	var entries map[string]map[string]string
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return Dictionary{}, fmt.Errorf("%s: %w", path, err)
+	}
+	d := Dictionary{Entries: map[string]map[string]string{}}
+	for lang, m := range entries {
+		d.Entries[lang] = make(map[string]string, len(m))
+		for k, v := range m {
+			d.Entries[lang][strings.ToLower(k)] = v
+		}
+	}
+	return d, nil
+}
+
+// New returns the provider TRANSLATE_PROVIDER names, or nil when machine
+// translation is off.
+func New(cfg config.Config) (Provider, error) {
+	switch cfg.TranslateProvider {
+	case "", "none":
+		return nil, nil
+	case "dictionary":
+		return LoadDictionary(cfg.TranslateDictionary)
+	case "http":
+		if cfg.TranslateURL == "" {
+			return nil, fmt.Errorf("TRANSLATE_URL is required for the http provider")
+		}
+		return &HTTP{URL: cfg.TranslateURL, APIKey: cfg.TranslateAPIKey}, nil
+	}
+	return nil, fmt.Errorf("unknown TRANSLATE_PROVIDER %q", cfg.TranslateProvider)
+}
@@ -0,0 +1,60 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDictionary(t *testing.T) {
+	d := Dictionary{Entries: map[string]map[string]string{
+		"si": {"pizza": "පීසා", "half price": "අඩක් මිලට", "free": "නොමිලේ"},
+	}}
+	tests := []struct {
+		text, to string
+		want     string
+		wantErr  error
+	}{
+		{"Half Price ", "si", "අඩක් මිලට", nil},
+		{"Free pizza today", "si", "නොමිලේ පීසා today", nil},
+		{"Nothing known", "si", "", ErrNoTranslation},
+		{"pizza", "ta", "", nil},
+	}
+	for _, tt := range tests {
+		got, err := d.Translate(context.Background(), tt.text, "en", tt.to)
+		switch {
+		case tt.to == "ta":
+			if err == nil {
+				t.Errorf("Translate(%q, %s): want error for a language without entries", tt.text, tt.to)
+			}
+		case tt.wantErr != nil:
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Translate(%q, %s) error = %v, want %v", tt.text, tt.to, err, tt.wantErr)
+			}
+		case err != nil || got != tt.want:
+			t.Errorf("Translate(%q, %s) = %q, %v; want %q", tt.text, tt.to, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "translate.json")
+	if err := os.WriteFile(path, []byte(`{"ta": {"Pizza": "பீஸ்ஸா"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := d.Translate(context.Background(), "PIZZA", "en", "ta"); err != nil || got != "பீஸ்ஸா" {
+		t.Errorf("Translate = %q, %v; want பீஸ்ஸா", got, err)
+	}
+	if err := os.WriteFile(path, []byte(`{"ta": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDictionary(path); err == nil {
+		t.Error("LoadDictionary of bad JSON: want error")
+	}
+}
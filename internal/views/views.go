@@ -4,8 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"go-next-cms/internal/i18n"
 	"go-next-cms/internal/models"
 
 	"github.com/a-h/templ"
@@ -15,7 +20,7 @@ type NavData struct {
 	CountryCode string
 	Lang        string
 	User        *models.User
-	T           func(string) string
+	T           i18n.Func
 }
 
 func Render(c templ.Component) (string, error) {
@@ -26,51 +31,285 @@ func Render(c templ.Component) (string, error) {
 	return b.String(), nil
 }
 
+//i18n:keys moderation translations users master_data config
+var adminNav = []struct {
+	href, key string
+	perm      models.Permission
+}{
+	{"/admin/moderation", "moderation", models.PermModerate},
+	{"/admin/translations", "translations", models.PermTranslate},
+	{"/admin/users", "users", models.PermManageUsers},
+	{"/admin/master", "master_data", models.PermManageMasterData},
+	{"/admin/config", "config", models.PermEditConfig},
+}
+
 func Layout(title string, nav NavData, content template.HTML) templ.Component {
 	return templ.ComponentFunc(func(ctx templ.Context, w templ.Writer) error {
 		_, err := fmt.Fprintf(w, `<!doctype html><html><head><meta charset='utf-8'><meta name='viewport' content='width=device-width, initial-scale=1'><script src='https://unpkg.com/htmx.org@1.9.12'></script><link rel='stylesheet' href='/static/css/app.css'><title>%s</title></head><body><header><nav><a href='/%s'>%s</a> | <a href='/%s/deals'>%s</a>`, title, nav.CountryCode, nav.T("home"), nav.CountryCode, nav.T("deals"))
 		if nav.User == nil {
 			fmt.Fprintf(w, ` | <a href='/account/login'>%s</a> | <a href='/account/register'>%s</a>`, nav.T("login"), nav.T("register"))
 		} else {
-			fmt.Fprintf(w, ` | <a href='/account/submissions'>%s</a> | <a href='/account/logout'>%s</a>`, nav.T("my_submissions"), nav.T("logout"))
-			if nav.User.Role == models.RoleAdmin {
+			fmt.Fprintf(w, ` | <a href='/account/submissions'>%s</a> | <a href='/account/sessions'>%s</a> | <a href='/account/logout'>%s</a>`, nav.T("my_submissions"), nav.T("sessions"), nav.T("logout"))
+			if nav.User.Role.IsStaff() {
 				fmt.Fprintf(w, ` | <a href='/admin'>%s</a>`, nav.T("admin"))
 			}
+			for _, l := range adminNav {
+				if nav.User.Can(l.perm) {
+					fmt.Fprintf(w, ` | <a href='%s'>%s</a>`, l.href, nav.T(l.key))
+				}
+			}
 		}
 		fmt.Fprintf(w, `</nav></header><main>%s</main></body></html>`, content)
 		return err
 	})
 }
 
-func DealCards(deals []models.Deal, countryCode string) template.HTML {
+func DealCards(deals []models.Deal, countryCode string, t i18n.Func) template.HTML {
 	var b bytes.Buffer
 	for _, d := range deals {
-		fmt.Fprintf(&b, "<article class='card'><h3><a href='/%s/deal/%s'>%s</a></h3><p>%s</p><small>%s - %s</small></article>", countryCode, d.Slug, template.HTMLEscapeString(d.Title), template.HTMLEscapeString(d.Description), d.CityName, d.EndAt.Format("2006-01-02"))
+		cc := countryCode
+		if d.CountryCode != "" {
+			cc = d.CountryCode
+		}
+		summary := template.HTMLEscapeString(d.Description)
+		if d.Snippet != "" {
+			summary = highlight(d.Snippet)
+		}
+		// end_at is the last instant of the final day, so whole days left
+		// round down: a deal ending tomorrow reads one day, today's none.
+		days := int(math.Floor(time.Until(d.EndAt).Hours() / 24))
+		if days < 0 {
+			days = 0
+		}
+		fmt.Fprintf(&b, "<article class='card'><h3><a href='/%s/deal/%s'>%s</a></h3><p>%s</p><small>%s - <time datetime='%s'>%s</time></small></article>", cc, d.Slug, template.HTMLEscapeString(d.Title), summary, template.HTMLEscapeString(d.CityName), d.EndAt.Format(time.DateOnly), t("deal_ends", "days", days))
 	}
 	if len(deals) == 0 {
-		b.WriteString("<p>No deals found.</p>")
+		fmt.Fprintf(&b, "<p>%s</p>", t("no_deals"))
 	}
 	return template.HTML(b.String())
 }
 
-func HomeContent(featured, ending []models.Deal, categories []models.Category, cc string, t func(string) string) template.HTML {
+func highlight(snippet string) string {
+	s := template.HTMLEscapeString(snippet)
+	s = strings.ReplaceAll(s, models.SnippetMarkStart, "<mark>")
+	return strings.ReplaceAll(s, models.SnippetMarkStop, "</mark>")
+}
+
+func HomeContent(featured, ending []models.Deal, categories []models.Category, cc string, t i18n.Func) template.HTML {
 	var b bytes.Buffer
-	fmt.Fprintf(&b, "<h1>%s</h1><section><h2>%s</h2>%s</section>", cc, t("featured_deals"), DealCards(featured, cc))
+	fmt.Fprintf(&b, "<h1>%s</h1><section><h2>%s</h2>%s</section>", cc, t("featured_deals"), DealCards(featured, cc, t))
 	fmt.Fprintf(&b, "<section><h2>%s</h2><ul>", t("categories"))
 	for _, c := range categories {
-		fmt.Fprintf(&b, "<li><a href='/%s/category/%s'>%s</a></li>", cc, c.Slug, c.Name)
+		fmt.Fprintf(&b, "<li><a href='/%s/category/%s'>%s</a></li>", cc, c.Slug, template.HTMLEscapeString(c.Name))
 	}
 	b.WriteString("</ul></section>")
-	fmt.Fprintf(&b, "<section><h2>%s</h2>%s</section>", t("ending_soon"), DealCards(ending, cc))
+	fmt.Fprintf(&b, "<section><h2>%s</h2>%s</section>", t("ending_soon"), DealCards(ending, cc, t))
+	return template.HTML(b.String())
+}
+
+func DealDetail(d *models.Deal, t i18n.Func) template.HTML {
+	meta := t("deal_meta", "category", template.HTMLEscapeString(d.CategoryName), "city", template.HTMLEscapeString(d.CityName), "type", template.HTMLEscapeString(d.DealTypeName))
+	valid := t("deal_valid", "start", d.StartAt.Format(time.DateOnly), "end", d.EndAt.Format(time.DateOnly))
+	return template.HTML(fmt.Sprintf("<article><h1>%s</h1><p>%s</p><p>%s</p><p>%s</p></article>", template.HTMLEscapeString(d.Title), template.HTMLEscapeString(d.Description), meta, valid))
+}
+
+func JobRuns(runs []models.JobRun, t i18n.Func) template.HTML {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<h2>%s</h2><table><tr><th>%s</th><th>%s</th><th>%s</th><th>%s</th></tr>", t("background_jobs"), t("job"), t("last_run"), t("duration"), t("job_error"))
+	for _, r := range runs {
+		errMsg := ""
+		if r.Error != nil {
+			errMsg = *r.Error
+		}
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>", r.JobName, r.StartedAt.Format(time.DateTime), r.Duration, template.HTMLEscapeString(errMsg))
+	}
+	if len(runs) == 0 {
+		fmt.Fprintf(&b, "<tr><td colspan='4'>%s</td></tr>", t("no_job_runs"))
+	}
+	b.WriteString("</table>")
+	return template.HTML(b.String())
+}
+
+func DealRevisions(d *models.Deal, revs []models.DealRevision, diffs [][]models.FieldChange, history []models.DealStatusEvent, csrf string, t i18n.Func) template.HTML {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<h1>%s</h1><p>%s</p>", t("revisions_of", "title", template.HTMLEscapeString(d.Title)), t("deal_status", "status", string(d.Status)))
+	if len(history) > 0 {
+		fmt.Fprintf(&b, "<h2>%s</h2><ul>", t("status_history"))
+		for _, e := range history {
+			name := t("system_actor")
+			if e.UserName != "" {
+				name = template.HTMLEscapeString(e.UserName)
+			}
+			fmt.Fprintf(&b, "<li>%s", t("status_changed", "from", t("deal_status_name", "status", string(e.From)), "to", t("deal_status_name", "status", string(e.To)), "name", name, "time", e.CreatedAt.Format(time.DateTime)))
+			if e.Reason != nil && *e.Reason != "" {
+				fmt.Fprintf(&b, ": %s", template.HTMLEscapeString(*e.Reason))
+			}
+			b.WriteString("</li>")
+		}
+		b.WriteString("</ul>")
+	}
+	for i, rv := range revs {
+		fmt.Fprintf(&b, "<section class='revision'><h3>%s</h3>", t("revision_by", "id", rv.ID, "name", template.HTMLEscapeString(rv.CreatedByName), "time", rv.CreatedAt.Format(time.DateTime)))
+		if rv.RestoredFromID != nil {
+			fmt.Fprintf(&b, "<p>%s</p>", t("restored_from", "id", *rv.RestoredFromID))
+		}
+		fmt.Fprintf(&b, "<table><tr><th>%s</th><th>%s</th><th>%s</th></tr>", t("field"), t("before"), t("after"))
+		for _, ch := range diffs[i] {
+			fmt.Fprintf(&b, "<tr><td>%s</td><td><del>%s</del></td><td><ins>%s</ins></td></tr>", ch.Field, template.HTMLEscapeString(ch.Old), template.HTMLEscapeString(ch.New))
+		}
+		if len(diffs[i]) == 0 {
+			fmt.Fprintf(&b, "<tr><td colspan='3'>%s</td></tr>", t("no_changes"))
+		}
+		b.WriteString("</table>")
+		if i > 0 {
+			fmt.Fprintf(&b, "<form method='post' action='/admin/deals/%d/revisions/%d/restore'><input type='hidden' name='csrf' value='%s'><button>%s</button></form>", d.ID, rv.ID, csrf, t("restore"))
+		}
+		b.WriteString("</section>")
+	}
+	return template.HTML(b.String())
+}
+
+func DealsURL(cc string, f models.DealFilter) string {
+	v := url.Values{}
+	if f.CitySlug != "" {
+		v.Set("city", f.CitySlug)
+	}
+	if f.CategorySlug != "" {
+		v.Set("category", f.CategorySlug)
+	}
+	if f.DealTypeID > 0 {
+		v.Set("deal_type", strconv.FormatInt(f.DealTypeID, 10))
+	}
+	if f.MerchantID > 0 {
+		v.Set("merchant", strconv.FormatInt(f.MerchantID, 10))
+	}
+	if f.Search != "" {
+		v.Set("q", f.Search)
+	}
+	if f.EndingSoon {
+		v.Set("ending_soon", "1")
+	}
+	if f.Page > 1 {
+		v.Set("page", strconv.Itoa(f.Page))
+	}
+	if len(v) == 0 {
+		return "/" + cc + "/deals"
+	}
+	return "/" + cc + "/deals?" + v.Encode()
+}
+
+func browseLink(href, label string) string {
+	h := template.HTMLEscapeString(href)
+	return fmt.Sprintf("<a href='%s' hx-get='%s' hx-target='#browse' hx-swap='outerHTML' hx-push-url='true'>%s</a>", h, h, label)
+}
+
+func facetList(b *bytes.Buffer, title string, counts []models.FacetCount, f models.DealFilter, cc string, selected string, set func(f *models.DealFilter, v string)) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "<h3>%s</h3><ul>", title)
+	for _, fc := range counts {
+		next := f
+		next.Page = 1
+		label := fmt.Sprintf("%s (%d)", template.HTMLEscapeString(fc.Label), fc.Count)
+		if fc.Value == selected {
+			set(&next, "")
+			label = "<strong>" + label + " &times;</strong>"
+		} else {
+			set(&next, fc.Value)
+		}
+		fmt.Fprintf(b, "<li>%s</li>", browseLink(DealsURL(cc, next), label))
+	}
+	b.WriteString("</ul>")
+}
+
+// DealBrowse renders the search form, facet sidebar, result list and
+// pagination as one block so htmx requests can swap it wholesale and the
+// form's hidden filters never go stale.
+func DealBrowse(res *models.DealListResult, f models.DealFilter, cc string, t i18n.Func) template.HTML {
+	var b bytes.Buffer
+	b.WriteString("<div id='browse' class='browse'>")
+	b.WriteString(searchForm(f, cc, t))
+	b.WriteString("<aside class='facets'>")
+	facetList(&b, t("cities"), res.Facets.Cities, f, cc, f.CitySlug, func(f *models.DealFilter, v string) { f.CitySlug = v })
+	facetList(&b, t("categories"), res.Facets.Categories, f, cc, f.CategorySlug, func(f *models.DealFilter, v string) { f.CategorySlug = v })
+	facetList(&b, t("deal_types"), res.Facets.DealTypes, f, cc, idValue(f.DealTypeID), func(f *models.DealFilter, v string) { f.DealTypeID, _ = strconv.ParseInt(v, 10, 64) })
+	facetList(&b, t("merchants"), res.Facets.Merchants, f, cc, idValue(f.MerchantID), func(f *models.DealFilter, v string) { f.MerchantID, _ = strconv.ParseInt(v, 10, 64) })
+	ending := f
+	ending.Page = 1
+	ending.EndingSoon = !f.EndingSoon
+	label := fmt.Sprintf("%s (%d)", t("ending_soon"), res.Facets.EndingSoon)
+	if f.EndingSoon {
+		label = "<strong>" + label + " &times;</strong>"
+	}
+	fmt.Fprintf(&b, "<h3>%s</h3><ul><li>%s</li></ul>", t("ending_soon"), browseLink(DealsURL(cc, ending), label))
+	fmt.Fprintf(&b, "</aside><section class='results'><p>%s</p>", t("deals_found", "count", res.Total))
+	b.WriteString(string(DealCards(res.Deals, cc, t)))
+	b.WriteString(string(pagination(res, f, cc, t)))
+	b.WriteString("</section></div>")
+	return template.HTML(b.String())
+}
+
+// searchForm searches within the active filters, carried as hidden inputs.
+func searchForm(f models.DealFilter, cc string, t i18n.Func) string {
+	var hidden strings.Builder
+	for _, kv := range [][2]string{{"city", f.CitySlug}, {"category", f.CategorySlug}, {"deal_type", idValue(f.DealTypeID)}, {"merchant", idValue(f.MerchantID)}} {
+		if kv[1] != "" {
+			fmt.Fprintf(&hidden, "<input type='hidden' name='%s' value='%s'>", kv[0], template.HTMLEscapeString(kv[1]))
+		}
+	}
+	if f.EndingSoon {
+		hidden.WriteString("<input type='hidden' name='ending_soon' value='1'>")
+	}
+	return fmt.Sprintf("<form class='search' action='/%s/deals' hx-get='/%s/deals' hx-target='#browse' hx-swap='outerHTML' hx-push-url='true'><input name='q' placeholder='%s' value='%s'>%s<button>%s</button></form>",
+		cc, cc, t("search_placeholder"), template.HTMLEscapeString(f.Search), hidden.String(), t("search"))
+}
+
+// pageWindow is how many page links pagination shows on each side of the
+// current page, besides the first and last.
+const pageWindow = 2
+
+func pagination(res *models.DealListResult, f models.DealFilter, cc string, t i18n.Func) template.HTML {
+	pages := (res.Total + res.PageSize - 1) / res.PageSize
+	if pages <= 1 {
+		return ""
+	}
+	var b bytes.Buffer
+	b.WriteString("<nav class='pagination'>")
+	page := func(n int, label string) {
+		p := f
+		p.Page = n
+		b.WriteString(browseLink(DealsURL(cc, p), label) + " ")
+	}
+	if res.Page > 1 {
+		page(res.Page-1, t("prev_page"))
+	}
+	gap := false
+	for n := 1; n <= pages; n++ {
+		if n != 1 && n != pages && (n < res.Page-pageWindow || n > res.Page+pageWindow) {
+			if !gap {
+				b.WriteString("&hellip; ")
+				gap = true
+			}
+			continue
+		}
+		gap = false
+		if n == res.Page {
+			fmt.Fprintf(&b, "<strong>%d</strong> ", n)
+			continue
+		}
+		page(n, strconv.Itoa(n))
+	}
+	if res.Page < pages {
+		page(res.Page+1, t("next_page"))
+	}
+	b.WriteString("</nav>")
 	return template.HTML(b.String())
 }
 
-func DealDetail(d *models.Deal, tr *models.DealTranslation) template.HTML {
-	title := d.Title
-	desc := d.Description
-	if tr != nil {
-		title = tr.Title
-		desc = tr.Description
+func idValue(id int64) string {
+	if id == 0 {
+		return ""
 	}
-	return template.HTML(fmt.Sprintf("<article><h1>%s</h1><p>%s</p><p>Category: %s | City: %s | Type: %s</p><p>Valid: %s to %s</p></article>", template.HTMLEscapeString(title), template.HTMLEscapeString(desc), d.CategoryName, d.CityName, d.DealTypeName, d.StartAt.Format(time.DateOnly), d.EndAt.Format(time.DateOnly)))
+	return strconv.FormatInt(id, 10)
 }
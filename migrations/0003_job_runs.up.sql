@@ -0,0 +1,9 @@
+CREATE TABLE job_runs (
+  id BIGSERIAL PRIMARY KEY,
+  job_name TEXT NOT NULL,
+  started_at TIMESTAMP NOT NULL,
+  duration_ms BIGINT NOT NULL,
+  error TEXT
+);
+
+CREATE INDEX idx_job_runs_name_started ON job_runs(job_name, started_at DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE deals DROP COLUMN IF EXISTS published_at;
+ALTER TABLE deals DROP COLUMN IF EXISTS approved_at;
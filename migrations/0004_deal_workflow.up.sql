@@ -0,0 +1,4 @@
+ALTER TABLE deals ADD COLUMN approved_at TIMESTAMP;
+ALTER TABLE deals ADD COLUMN published_at TIMESTAMP;
+
+UPDATE deals SET published_at=created_at WHERE status IN ('published','expired');
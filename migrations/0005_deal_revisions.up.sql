@@ -0,0 +1,25 @@
+CREATE TABLE deal_revisions (
+  id BIGSERIAL PRIMARY KEY,
+  deal_id BIGINT NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+  snapshot JSONB NOT NULL,
+  created_by_user_id BIGINT NOT NULL REFERENCES users(id),
+  restored_from_id BIGINT REFERENCES deal_revisions(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_deal_revisions_deal ON deal_revisions(deal_id, id DESC);
+
+INSERT INTO deal_revisions (deal_id,snapshot,created_by_user_id,created_at)
+SELECT d.id, jsonb_build_object(
+  'Title', d.title,
+  'Description', d.description,
+  'CityID', d.city_id,
+  'CategoryID', d.category_id,
+  'MerchantID', d.merchant_id,
+  'DealTypeID', d.deal_type_id,
+  'StartAt', to_char(d.start_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+  'EndAt', to_char(d.end_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+  'ImageURL', COALESCE(d.image_url, ''),
+  'Translations', COALESCE((SELECT jsonb_agg(jsonb_build_object('DealID', t.deal_id, 'Lang', t.lang, 'Title', t.title, 'Description', t.description) ORDER BY t.lang) FROM deal_translations t WHERE t.deal_id=d.id), '[]'::jsonb)
+), d.created_by_user_id, d.updated_at
+FROM deals d;
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS trg_merchants_search ON merchants;
+DROP TRIGGER IF EXISTS trg_categories_search ON categories;
+DROP TRIGGER IF EXISTS trg_cities_search ON cities;
+DROP TRIGGER IF EXISTS trg_deal_translations_search ON deal_translations;
+DROP TRIGGER IF EXISTS trg_deals_search ON deals;
+DROP FUNCTION IF EXISTS master_data_search_trigger();
+DROP FUNCTION IF EXISTS deal_translations_search_trigger();
+DROP FUNCTION IF EXISTS deals_search_trigger();
+DROP FUNCTION IF EXISTS refresh_deal_search(BIGINT);
+DROP TABLE IF EXISTS deal_search;
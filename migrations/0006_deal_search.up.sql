@@ -0,0 +1,71 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE deal_search (
+  deal_id BIGINT PRIMARY KEY REFERENCES deals(id) ON DELETE CASCADE,
+  document TSVECTOR NOT NULL,
+  content TEXT NOT NULL
+);
+
+CREATE INDEX idx_deal_search_document ON deal_search USING GIN(document);
+CREATE INDEX idx_deal_search_content_trgm ON deal_search USING GIN(content gin_trgm_ops);
+
+-- Sinhala and Tamil have no Postgres stemmer, so translations are indexed
+-- with the 'simple' configuration alongside the stemmed English columns.
+CREATE FUNCTION refresh_deal_search(p_deal_id BIGINT) RETURNS void AS $$
+  INSERT INTO deal_search (deal_id, document, content)
+  SELECT d.id,
+    setweight(to_tsvector('english', d.title), 'A') ||
+    setweight(to_tsvector('simple', d.title || ' ' || COALESCE(string_agg(t.title, ' '), '')), 'A') ||
+    setweight(to_tsvector('english', d.description), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(string_agg(t.description, ' '), '')), 'B') ||
+    setweight(to_tsvector('simple', concat_ws(' ', m.name, ci.name, ca.name)), 'C'),
+    concat_ws(' ', d.title, d.description, string_agg(t.title || ' ' || t.description, ' '), m.name, ci.name, ca.name)
+  FROM deals d
+  JOIN cities ci ON ci.id=d.city_id
+  JOIN categories ca ON ca.id=d.category_id
+  LEFT JOIN merchants m ON m.id=d.merchant_id
+  LEFT JOIN deal_translations t ON t.deal_id=d.id AND t.lang<>'en'
+  WHERE d.id=p_deal_id
+  GROUP BY d.id, m.name, ci.name, ca.name
+  ON CONFLICT (deal_id) DO UPDATE SET document=EXCLUDED.document, content=EXCLUDED.content;
+$$ LANGUAGE sql;
+
+CREATE FUNCTION deals_search_trigger() RETURNS trigger AS $$
+BEGIN
+  PERFORM refresh_deal_search(NEW.id);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_deals_search AFTER INSERT OR UPDATE OF title, description, city_id, category_id, merchant_id ON deals
+FOR EACH ROW EXECUTE FUNCTION deals_search_trigger();
+
+CREATE FUNCTION deal_translations_search_trigger() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM refresh_deal_search(OLD.deal_id);
+  ELSE
+    PERFORM refresh_deal_search(NEW.deal_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_deal_translations_search AFTER INSERT OR UPDATE OR DELETE ON deal_translations
+FOR EACH ROW EXECUTE FUNCTION deal_translations_search_trigger();
+
+CREATE FUNCTION master_data_search_trigger() RETURNS trigger AS $$
+BEGIN
+  PERFORM refresh_deal_search(d.id) FROM deals d
+  WHERE (TG_TABLE_NAME = 'cities' AND d.city_id = NEW.id)
+     OR (TG_TABLE_NAME = 'categories' AND d.category_id = NEW.id)
+     OR (TG_TABLE_NAME = 'merchants' AND d.merchant_id = NEW.id);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_cities_search AFTER UPDATE OF name ON cities FOR EACH ROW EXECUTE FUNCTION master_data_search_trigger();
+CREATE TRIGGER trg_categories_search AFTER UPDATE OF name ON categories FOR EACH ROW EXECUTE FUNCTION master_data_search_trigger();
+CREATE TRIGGER trg_merchants_search AFTER UPDATE OF name ON merchants FOR EACH ROW EXECUTE FUNCTION master_data_search_trigger();
+
+SELECT refresh_deal_search(id) FROM deals;
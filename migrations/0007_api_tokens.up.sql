@@ -0,0 +1,15 @@
+CREATE TABLE api_tokens (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  prefix TEXT UNIQUE NOT NULL,
+  token_hash TEXT NOT NULL,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  rate_limit INT NOT NULL DEFAULT 60,
+  expires_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  last_used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_tokens_user ON api_tokens(user_id);
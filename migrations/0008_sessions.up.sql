@@ -0,0 +1,14 @@
+CREATE TABLE sessions (
+  id BIGSERIAL PRIMARY KEY,
+  sid TEXT UNIQUE NOT NULL,
+  data BYTEA NOT NULL,
+  user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT '',
+  expires_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  last_seen_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_sessions_user ON sessions(user_id);
+CREATE INDEX idx_sessions_expires ON sessions(expires_at);
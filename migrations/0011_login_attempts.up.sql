@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN failed_login_count INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN last_failed_login_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
+
+CREATE TABLE login_attempts (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  email TEXT NOT NULL,
+  ip TEXT NOT NULL,
+  user_agent TEXT NOT NULL DEFAULT '',
+  success BOOLEAN NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_attempts_ip ON login_attempts(ip, created_at);
+CREATE INDEX idx_login_attempts_created ON login_attempts(created_at DESC);
@@ -0,0 +1,5 @@
+CREATE TABLE user_countries (
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  country_id BIGINT NOT NULL REFERENCES countries(id) ON DELETE CASCADE,
+  PRIMARY KEY (user_id, country_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN preferred_lang TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE countries ADD COLUMN languages TEXT[] NOT NULL DEFAULT '{}';
+UPDATE countries SET languages = ARRAY['en','si','ta'] WHERE code='LK';
@@ -0,0 +1,7 @@
+CREATE TABLE master_translations (
+  entity TEXT NOT NULL CHECK (entity IN ('city','category','deal_type','merchant')),
+  entity_id BIGINT NOT NULL,
+  lang VARCHAR(2) NOT NULL,
+  name TEXT NOT NULL,
+  PRIMARY KEY (entity, entity_id, lang)
+);
@@ -0,0 +1 @@
+ALTER TABLE countries DROP COLUMN IF EXISTS fallbacks;
@@ -0,0 +1 @@
+ALTER TABLE countries ADD COLUMN fallbacks TEXT NOT NULL DEFAULT '';
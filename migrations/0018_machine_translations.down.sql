@@ -0,0 +1 @@
+ALTER TABLE deal_translations DROP COLUMN IF EXISTS machine;
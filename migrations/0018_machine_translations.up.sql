@@ -0,0 +1 @@
+ALTER TABLE deal_translations ADD COLUMN machine BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,7 @@
+UPDATE users SET role='submitter' WHERE role='translator';
+ALTER TYPE user_role RENAME TO user_role_old;
+CREATE TYPE user_role AS ENUM ('submitter','moderator','admin');
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::text::user_role;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'submitter';
+DROP TYPE user_role_old;
@@ -0,0 +1,9 @@
+ALTER TABLE deal_translations ADD COLUMN machine BOOLEAN NOT NULL DEFAULT false;
+UPDATE deal_translations SET machine=true WHERE status='machine';
+ALTER TABLE deal_translations
+  DROP COLUMN status,
+  DROP COLUMN author_user_id,
+  DROP COLUMN reviewed_by_user_id,
+  DROP COLUMN created_at,
+  DROP COLUMN updated_at,
+  DROP COLUMN reviewed_at;
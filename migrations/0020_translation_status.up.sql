@@ -0,0 +1,9 @@
+ALTER TABLE deal_translations
+  ADD COLUMN status TEXT NOT NULL DEFAULT 'draft' CHECK (status IN ('draft','machine','reviewed')),
+  ADD COLUMN author_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN reviewed_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ADD COLUMN reviewed_at TIMESTAMPTZ;
+UPDATE deal_translations SET status='machine' WHERE machine;
+ALTER TABLE deal_translations DROP COLUMN machine;
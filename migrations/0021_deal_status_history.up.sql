@@ -0,0 +1,12 @@
+CREATE TABLE deal_status_history (
+  id BIGSERIAL PRIMARY KEY,
+  deal_id BIGINT NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+  from_status deal_status NOT NULL,
+  to_status deal_status NOT NULL,
+  actor TEXT NOT NULL,
+  user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  reason TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_deal_status_history_deal ON deal_status_history(deal_id, id DESC);
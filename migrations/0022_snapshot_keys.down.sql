@@ -0,0 +1,15 @@
+UPDATE deal_revisions SET snapshot = jsonb_build_object(
+  'Title', snapshot->'title',
+  'Description', snapshot->'description',
+  'CityID', snapshot->'city_id',
+  'CategoryID', snapshot->'category_id',
+  'MerchantID', snapshot->'merchant_id',
+  'DealTypeID', snapshot->'deal_type_id',
+  'StartAt', snapshot->'start_at',
+  'EndAt', snapshot->'end_at',
+  'ImageURL', snapshot->'image_url',
+  'Translations', COALESCE((
+    SELECT jsonb_agg(jsonb_build_object('DealID', d.id, 'Lang', e.t->'lang', 'Title', e.t->'title', 'Description', e.t->'description') ORDER BY e.n)
+    FROM jsonb_array_elements(CASE WHEN jsonb_typeof(snapshot->'translations')='array' THEN snapshot->'translations' ELSE '[]'::jsonb END) WITH ORDINALITY AS e(t, n)
+  ), '[]'::jsonb)
+) FROM deals d WHERE d.id=deal_revisions.deal_id AND snapshot ? 'title';
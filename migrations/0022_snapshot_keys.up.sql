@@ -0,0 +1,16 @@
+-- Revision snapshots were keyed on Go field names; give them stable keys.
+UPDATE deal_revisions SET snapshot = jsonb_build_object(
+  'title', snapshot->'Title',
+  'description', snapshot->'Description',
+  'city_id', snapshot->'CityID',
+  'category_id', snapshot->'CategoryID',
+  'merchant_id', snapshot->'MerchantID',
+  'deal_type_id', snapshot->'DealTypeID',
+  'start_at', snapshot->'StartAt',
+  'end_at', snapshot->'EndAt',
+  'image_url', snapshot->'ImageURL',
+  'translations', COALESCE((
+    SELECT jsonb_agg(jsonb_build_object('lang', e.t->'Lang', 'title', e.t->'Title', 'description', e.t->'Description') ORDER BY e.n)
+    FROM jsonb_array_elements(CASE WHEN jsonb_typeof(snapshot->'Translations')='array' THEN snapshot->'Translations' ELSE '[]'::jsonb END) WITH ORDINALITY AS e(t, n)
+  ), '[]'::jsonb)
+) WHERE snapshot ? 'Title';
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN all_countries BOOLEAN NOT NULL DEFAULT false;
+
+-- Staff without assignments used to act in every country; keep them global.
+UPDATE users SET all_countries = true
+WHERE role <> 'submitter' AND NOT EXISTS (SELECT 1 FROM user_countries uc WHERE uc.user_id = users.id);
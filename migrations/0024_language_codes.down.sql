@@ -0,0 +1,3 @@
+ALTER TABLE master_translations ALTER COLUMN lang TYPE VARCHAR(2);
+ALTER TABLE deal_translations ALTER COLUMN lang TYPE VARCHAR(2);
+ALTER TABLE countries ALTER COLUMN default_language TYPE VARCHAR(2);
@@ -0,0 +1,4 @@
+-- Room for three-letter ISO 639 codes of languages without a two-letter one.
+ALTER TABLE countries ALTER COLUMN default_language TYPE VARCHAR(3);
+ALTER TABLE deal_translations ALTER COLUMN lang TYPE VARCHAR(3);
+ALTER TABLE master_translations ALTER COLUMN lang TYPE VARCHAR(3);
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS trg_merchants_translations ON merchants;
+DROP TRIGGER IF EXISTS trg_deal_types_translations ON deal_types;
+DROP TRIGGER IF EXISTS trg_categories_translations ON categories;
+DROP TRIGGER IF EXISTS trg_cities_translations ON cities;
+DROP TRIGGER IF EXISTS trg_master_translations_ref ON master_translations;
+DROP FUNCTION IF EXISTS master_data_translations_trigger();
+DROP FUNCTION IF EXISTS master_translations_ref_trigger();
@@ -0,0 +1,41 @@
+-- master_translations points at one of several tables, so a foreign key
+-- cannot express it; these triggers stand in for one.
+DELETE FROM master_translations mt
+WHERE (mt.entity = 'city' AND NOT EXISTS (SELECT 1 FROM cities x WHERE x.id = mt.entity_id))
+   OR (mt.entity = 'category' AND NOT EXISTS (SELECT 1 FROM categories x WHERE x.id = mt.entity_id))
+   OR (mt.entity = 'deal_type' AND NOT EXISTS (SELECT 1 FROM deal_types x WHERE x.id = mt.entity_id))
+   OR (mt.entity = 'merchant' AND NOT EXISTS (SELECT 1 FROM merchants x WHERE x.id = mt.entity_id));
+
+CREATE FUNCTION master_translations_ref_trigger() RETURNS trigger AS $$
+DECLARE
+  found BOOLEAN;
+BEGIN
+  found := CASE NEW.entity
+    WHEN 'city' THEN EXISTS (SELECT 1 FROM cities WHERE id = NEW.entity_id)
+    WHEN 'category' THEN EXISTS (SELECT 1 FROM categories WHERE id = NEW.entity_id)
+    WHEN 'deal_type' THEN EXISTS (SELECT 1 FROM deal_types WHERE id = NEW.entity_id)
+    WHEN 'merchant' THEN EXISTS (SELECT 1 FROM merchants WHERE id = NEW.entity_id)
+    ELSE false
+  END;
+  IF NOT found THEN
+    RAISE foreign_key_violation USING MESSAGE = format('%s %s does not exist', NEW.entity, NEW.entity_id);
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_master_translations_ref BEFORE INSERT OR UPDATE OF entity, entity_id ON master_translations
+FOR EACH ROW EXECUTE FUNCTION master_translations_ref_trigger();
+
+-- TG_ARGV[0] is the entity name of the table the trigger is on.
+CREATE FUNCTION master_data_translations_trigger() RETURNS trigger AS $$
+BEGIN
+  DELETE FROM master_translations WHERE entity = TG_ARGV[0] AND entity_id = OLD.id;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_cities_translations AFTER DELETE ON cities FOR EACH ROW EXECUTE FUNCTION master_data_translations_trigger('city');
+CREATE TRIGGER trg_categories_translations AFTER DELETE ON categories FOR EACH ROW EXECUTE FUNCTION master_data_translations_trigger('category');
+CREATE TRIGGER trg_deal_types_translations AFTER DELETE ON deal_types FOR EACH ROW EXECUTE FUNCTION master_data_translations_trigger('deal_type');
+CREATE TRIGGER trg_merchants_translations AFTER DELETE ON merchants FOR EACH ROW EXECUTE FUNCTION master_data_translations_trigger('merchant');
@@ -0,0 +1,10 @@
+-- Failed machine translations, so draft_translations backs off and
+-- eventually gives up instead of retrying the same deal every run.
+CREATE TABLE translation_attempts (
+  deal_id BIGINT NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+  lang VARCHAR(3) NOT NULL,
+  attempts INT NOT NULL,
+  last_error TEXT NOT NULL DEFAULT '',
+  next_attempt_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (deal_id, lang)
+);
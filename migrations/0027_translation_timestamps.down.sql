@@ -0,0 +1,4 @@
+ALTER TABLE deal_translations
+  ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+  ALTER COLUMN updated_at TYPE TIMESTAMPTZ,
+  ALTER COLUMN reviewed_at TYPE TIMESTAMPTZ;
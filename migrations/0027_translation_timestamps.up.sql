@@ -0,0 +1,5 @@
+-- Every other table uses TIMESTAMP; 0020 added these as TIMESTAMPTZ.
+ALTER TABLE deal_translations
+  ALTER COLUMN created_at TYPE TIMESTAMP,
+  ALTER COLUMN updated_at TYPE TIMESTAMP,
+  ALTER COLUMN reviewed_at TYPE TIMESTAMP;
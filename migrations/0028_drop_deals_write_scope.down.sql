@@ -0,0 +1,2 @@
+-- Tokens that had deals:write cannot be told apart any more; nothing to restore.
+SELECT 1;
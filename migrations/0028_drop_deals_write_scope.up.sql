@@ -0,0 +1,2 @@
+-- deals:write was never enforced by any route and is no longer offered.
+UPDATE api_tokens SET scopes = array_remove(scopes, 'deals:write');
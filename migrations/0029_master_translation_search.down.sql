@@ -0,0 +1,24 @@
+DROP TRIGGER IF EXISTS trg_master_translations_search ON master_translations;
+DROP FUNCTION IF EXISTS master_translations_search_trigger();
+DROP FUNCTION IF EXISTS refresh_master_deal_search(TEXT, BIGINT);
+
+CREATE OR REPLACE FUNCTION refresh_deal_search(p_deal_id BIGINT) RETURNS void AS $$
+  INSERT INTO deal_search (deal_id, document, content)
+  SELECT d.id,
+    setweight(to_tsvector('english', d.title), 'A') ||
+    setweight(to_tsvector('simple', d.title || ' ' || COALESCE(string_agg(t.title, ' '), '')), 'A') ||
+    setweight(to_tsvector('english', d.description), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(string_agg(t.description, ' '), '')), 'B') ||
+    setweight(to_tsvector('simple', concat_ws(' ', m.name, ci.name, ca.name)), 'C'),
+    concat_ws(' ', d.title, d.description, string_agg(t.title || ' ' || t.description, ' '), m.name, ci.name, ca.name)
+  FROM deals d
+  JOIN cities ci ON ci.id=d.city_id
+  JOIN categories ca ON ca.id=d.category_id
+  LEFT JOIN merchants m ON m.id=d.merchant_id
+  LEFT JOIN deal_translations t ON t.deal_id=d.id AND t.lang<>'en'
+  WHERE d.id=p_deal_id
+  GROUP BY d.id, m.name, ci.name, ca.name
+  ON CONFLICT (deal_id) DO UPDATE SET document=EXCLUDED.document, content=EXCLUDED.content;
+$$ LANGUAGE sql;
+
+SELECT refresh_deal_search(id) FROM deals;
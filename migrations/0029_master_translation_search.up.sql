@@ -0,0 +1,53 @@
+-- Index the translated city, category and merchant names next to the
+-- default ones, so a search in Sinhala or Tamil finds deals by place and
+-- merchant too.
+CREATE OR REPLACE FUNCTION refresh_deal_search(p_deal_id BIGINT) RETURNS void AS $$
+  INSERT INTO deal_search (deal_id, document, content)
+  SELECT d.id,
+    setweight(to_tsvector('english', d.title), 'A') ||
+    setweight(to_tsvector('simple', d.title || ' ' || COALESCE(string_agg(t.title, ' '), '')), 'A') ||
+    setweight(to_tsvector('english', d.description), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(string_agg(t.description, ' '), '')), 'B') ||
+    setweight(to_tsvector('simple', concat_ws(' ', m.name, ci.name, ca.name, mt.names)), 'C'),
+    concat_ws(' ', d.title, d.description, string_agg(t.title || ' ' || t.description, ' '), m.name, ci.name, ca.name, mt.names)
+  FROM deals d
+  JOIN cities ci ON ci.id=d.city_id
+  JOIN categories ca ON ca.id=d.category_id
+  LEFT JOIN merchants m ON m.id=d.merchant_id
+  LEFT JOIN deal_translations t ON t.deal_id=d.id AND t.lang<>'en'
+  CROSS JOIN LATERAL (
+    SELECT string_agg(x.name, ' ') AS names FROM master_translations x
+    WHERE (x.entity = 'city' AND x.entity_id = d.city_id)
+       OR (x.entity = 'category' AND x.entity_id = d.category_id)
+       OR (x.entity = 'merchant' AND x.entity_id = d.merchant_id)
+  ) mt
+  WHERE d.id=p_deal_id
+  GROUP BY d.id, m.name, ci.name, ca.name, mt.names
+  ON CONFLICT (deal_id) DO UPDATE SET document=EXCLUDED.document, content=EXCLUDED.content;
+$$ LANGUAGE sql;
+
+CREATE FUNCTION refresh_master_deal_search(p_entity TEXT, p_entity_id BIGINT) RETURNS void AS $$
+BEGIN
+  PERFORM refresh_deal_search(d.id) FROM deals d
+  WHERE (p_entity = 'city' AND d.city_id = p_entity_id)
+     OR (p_entity = 'category' AND d.category_id = p_entity_id)
+     OR (p_entity = 'merchant' AND d.merchant_id = p_entity_id);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION master_translations_search_trigger() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    PERFORM refresh_master_deal_search(OLD.entity, OLD.entity_id);
+  END IF;
+  IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND (NEW.entity, NEW.entity_id) IS DISTINCT FROM (OLD.entity, OLD.entity_id)) THEN
+    PERFORM refresh_master_deal_search(NEW.entity, NEW.entity_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_master_translations_search AFTER INSERT OR UPDATE OR DELETE ON master_translations
+FOR EACH ROW EXECUTE FUNCTION master_translations_search_trigger();
+
+SELECT refresh_deal_search(id) FROM deals;
@@ -0,0 +1,7 @@
+UPDATE deals SET end_at = date_trunc('day', end_at)
+WHERE end_at = date_trunc('day', end_at) + INTERVAL '1 day' - INTERVAL '1 microsecond';
+
+UPDATE deal_revisions SET snapshot = jsonb_set(snapshot, '{end_at}', to_jsonb(replace(snapshot->>'end_at', 'T23:59:59.999999Z', 'T00:00:00Z')))
+WHERE snapshot->>'end_at' LIKE '%T23:59:59.999999Z';
+UPDATE deal_revisions SET snapshot = jsonb_set(snapshot, '{EndAt}', to_jsonb(replace(snapshot->>'EndAt', 'T23:59:59.999999Z', 'T00:00:00Z')))
+WHERE snapshot->>'EndAt' LIKE '%T23:59:59.999999Z';
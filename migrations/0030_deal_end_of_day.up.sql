@@ -0,0 +1,12 @@
+-- end_at is now the last instant of a deal's final day; it was midnight at
+-- the start of that day, which hid one-day deals and cut every deal a day
+-- short.
+UPDATE deals SET end_at = end_at + INTERVAL '1 day' - INTERVAL '1 microsecond'
+WHERE end_at = date_trunc('day', end_at);
+
+-- Revisions hold the old end too; fix them so restoring one does not bring
+-- the short end back.
+UPDATE deal_revisions SET snapshot = jsonb_set(snapshot, '{end_at}', to_jsonb(replace(snapshot->>'end_at', 'T00:00:00Z', 'T23:59:59.999999Z')))
+WHERE snapshot->>'end_at' LIKE '%T00:00:00Z';
+UPDATE deal_revisions SET snapshot = jsonb_set(snapshot, '{EndAt}', to_jsonb(replace(snapshot->>'EndAt', 'T00:00:00Z', 'T23:59:59.999999Z')))
+WHERE snapshot->>'EndAt' LIKE '%T00:00:00Z';